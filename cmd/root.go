@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/nicjohnson145/poke/config"
 	"github.com/nicjohnson145/poke/internal"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func Root() *cobra.Command {
@@ -27,6 +33,22 @@ func Root() *cobra.Command {
 			})
 			client.SetTimeout(10 * time.Second)
 
+			variables, err := loadVariables(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			reports := viper.GetStringSlice(config.Report)
+			reporter, err := internal.NewReporter(reports)
+			if err != nil {
+				return err
+			}
+			// Keep the report the only thing on stdout, so it can be consumed as is
+			var output io.Writer = os.Stdout
+			if internal.ReportsToStdout(reports) {
+				output = os.Stderr
+			}
+
 			runner := internal.NewRunner(internal.RunnerOpts{
 				Logger: config.WithComponent(logger, "runner"),
 				HttpExecutor: internal.NewHTTPExecutor(internal.HTTPExecutorOpts{
@@ -39,17 +61,60 @@ func Root() *cobra.Command {
 				Parser: internal.NewFSParser(internal.FSParserOpts{
 					Logger: config.WithComponent(logger, "fsparser"),
 				}),
-				Output: os.Stdout,
+				Output:          output,
+				Concurrency:     viper.GetInt(config.Concurrency),
+				LogOutput:       config.LogWriter(),
+				Reporter:        reporter,
+				Variables:       variables,
+				Hooks:           viper.GetString(config.Hooks),
+				Seed:            viper.GetInt64(config.Seed),
+				TeardownTimeout: viper.GetDuration(config.TeardownTimeout),
 			})
-			return runner.Run(args[0])
+
+			// Stop executing calls on interrupt, leaving time for teardowns to clean up. Once interrupted,
+			// go back to the default handling so a second interrupt kills the process outright
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				stop()
+			}()
+			return errors.Join(runner.RunContext(ctx, args[0]), reporter.Close())
 		},
 	}
 	rootCmd.PersistentFlags().BoolP(config.Debug, "d", false, "Enable debug logging")
 	rootCmd.Flags().BoolP(config.FailFast, "f", false, "Stop execution on first sequence failure")
+	rootCmd.Flags().IntP(config.Concurrency, "c", 1, "Number of sequences to execute in parallel")
+	addVariableFlags(rootCmd)
+	rootCmd.Flags().StringSlice(config.Report, nil, "Write a report of the results, as kind=path (e.g. junit=report.xml, json). Can be given multiple times")
+	rootCmd.Flags().String(config.Hooks, "", "A sequence file whose setup is executed before, and teardown after, every other sequence")
+	rootCmd.Flags().Duration(config.TeardownTimeout, 5*time.Minute, "How long the teardown of each sequence, or of the hooks file, may take before it's given up on")
+	rootCmd.Flags().Int64(config.Seed, 0, "Seed for the random template functions, so generated values can be reproduced. Defaults to a random seed")
 
 	rootCmd.AddCommand(
 		versionCmd(),
+		validateCmd(),
 	)
 
 	return rootCmd
 }
+
+func addVariableFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(config.Env, "e", "", "Load variables for the named environment, from envs/<name>.yaml or poke.envs.yaml")
+	cmd.Flags().StringArray(config.VarFile, nil, "Load variables from a YAML file, overriding sequence vars. Can be given multiple times")
+	cmd.Flags().StringArray(config.Var, nil, "Set a variable as key=value, overriding sequence vars and var files. Can be given multiple times")
+}
+
+func loadVariables(cmd *cobra.Command, root string) (internal.Variables, error) {
+	// Read directly from the flags, rather than through viper, so they can't be picked up from unrelated
+	// environment variables such as ENV
+	env, _ := cmd.Flags().GetString(config.Env)
+	varFiles, _ := cmd.Flags().GetStringArray(config.VarFile)
+	vars, _ := cmd.Flags().GetStringArray(config.Var)
+	return internal.LoadVariables(internal.VariablesOpts{
+		Root:     root,
+		Env:      env,
+		VarFiles: varFiles,
+		Vars:     vars,
+	})
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/nicjohnson145/poke/config"
+	"github.com/nicjohnson145/poke/internal"
+	"github.com/spf13/cobra"
+)
+
+func validateCmd() *cobra.Command {
+	validate := &cobra.Command{
+		Use:   "validate",
+		Short: "Check sequences for problems without executing them",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := config.InitLogger()
+
+			variables, err := loadVariables(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			hooks, _ := cmd.Flags().GetString(config.Hooks)
+
+			validator := internal.NewValidator(internal.ValidatorOpts{
+				Logger: config.WithComponent(logger, "validator"),
+				Parser: internal.NewFSParser(internal.FSParserOpts{
+					Logger: config.WithComponent(logger, "fsparser"),
+				}),
+				Variables: variables,
+				Hooks:     hooks,
+			})
+			problems, err := validator.Validate(args[0])
+			if err != nil {
+				return err
+			}
+
+			errCount := 0
+			for _, problem := range problems {
+				fmt.Println(problem)
+				if !problem.Warning {
+					errCount++
+				}
+			}
+			if errCount > 0 {
+				return fmt.Errorf("found %v error(s)", errCount)
+			}
+			return nil
+		},
+	}
+	addVariableFlags(validate)
+	validate.Flags().String(config.Hooks, "", "The hooks file that will be given to the run, whose setup exports are available to every sequence")
+
+	return validate
+}
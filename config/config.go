@@ -8,8 +8,16 @@ import (
 )
 
 const (
-	Debug    = "debug"
-	FailFast = "fail-fast"
+	Debug           = "debug"
+	FailFast        = "fail-fast"
+	Concurrency     = "concurrency"
+	Report          = "report"
+	Env             = "env"
+	VarFile         = "var-file"
+	Var             = "var"
+	Hooks           = "hooks"
+	Seed            = "seed"
+	TeardownTimeout = "teardown-timeout"
 )
 
 func InitializeConfig(cmd *cobra.Command) error {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"io"
 	"os"
 
 	"github.com/rs/zerolog"
@@ -9,7 +10,7 @@ import (
 )
 
 func InitLogger() zerolog.Logger {
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	log.Logger = log.Output(LogWriter())
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	if viper.GetBool(Debug) {
 		zerolog.SetGlobalLevel(zerolog.DebugLevel)
@@ -20,3 +21,8 @@ func InitLogger() zerolog.Logger {
 func WithComponent(logger zerolog.Logger, name string) zerolog.Logger {
 	return logger.With().Str("component", name).Logger()
 }
+
+// LogWriter returns the writer that formats log output
+func LogWriter() io.Writer {
+	return zerolog.ConsoleWriter{Out: os.Stderr}
+}
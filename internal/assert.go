@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var ErrAssertFailed = errors.New("failed assert")
+
+// AssertError describes a single failed assertion
+type AssertError struct {
+	Op       AssertOp
+	JQ       string
+	Expected any
+	Actual   any
+	Diff     string
+	Reason   string
+	// Pos is where the assert was defined, if known
+	Pos Position
+}
+
+func (a *AssertError) Error() string {
+	msg := fmt.Sprintf("%v: %v %v: expected %v, got %v", ErrAssertFailed, a.JQ, a.Op, formatValue(a.Expected), formatValue(a.Actual))
+	if a.Reason != "" {
+		msg += " (" + a.Reason + ")"
+	}
+	if a.Pos.File != "" {
+		msg = a.Pos.String() + ": " + msg
+	}
+	return msg
+}
+
+func (a *AssertError) Unwrap() error {
+	return ErrAssertFailed
+}
+
+// AssertResult is the outcome of evaluating a single assert against a response
+type AssertResult struct {
+	Assert Assert
+	Actual any
+	// Err describes why the assert failed, it's nil if the assert passed
+	Err *AssertError
+}
+
+// AssertionsError collects every failed assertion for a single call
+type AssertionsError struct {
+	Call     string
+	Failures []*AssertError
+}
+
+func (a *AssertionsError) Error() string {
+	msgs := make([]string, 0, len(a.Failures))
+	for _, failure := range a.Failures {
+		msgs = append(msgs, failure.Error())
+	}
+	return fmt.Sprintf("call %v failed %v assert(s): %v", a.Call, len(a.Failures), strings.Join(msgs, "; "))
+}
+
+func (a *AssertionsError) Unwrap() []error {
+	errs := make([]error, 0, len(a.Failures))
+	for _, failure := range a.Failures {
+		errs = append(errs, failure)
+	}
+	return errs
+}
+
+// evaluateAssert checks the actual value pulled out by the asserts jq against its expectation, returning an
+// *AssertError if the check does not pass
+func evaluateAssert(ass Assert, actual any) error {
+	op := ass.GetOp()
+	expected := normalizeValue(ass.Expected)
+	actual = normalizeValue(actual)
+
+	fail := func(reason string) error {
+		return &AssertError{
+			Op:       op,
+			JQ:       ass.JQ,
+			Expected: ass.Expected,
+			Actual:   actual,
+			Reason:   reason,
+		}
+	}
+
+	switch op {
+	case AssertOpEqual:
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			err := fail("").(*AssertError)
+			err.Diff = diff
+			return err
+		}
+	case AssertOpNotEqual:
+		if cmp.Equal(expected, actual) {
+			return fail("")
+		}
+	case AssertOpContains:
+		ok, err := containsValue(actual, expected)
+		if err != nil {
+			return fail(err.Error())
+		}
+		if !ok {
+			return fail("")
+		}
+	case AssertOpMatches:
+		pattern, ok := expected.(string)
+		if !ok {
+			return fail("expected must be a regular expression string")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fail(fmt.Sprintf("invalid regular expression: %v", err))
+		}
+		str, ok := actual.(string)
+		if !ok {
+			return fail("actual value is not a string")
+		}
+		if !re.MatchString(str) {
+			return fail("")
+		}
+	case AssertOpGt, AssertOpGte, AssertOpLt, AssertOpLte:
+		want, ok := expected.(float64)
+		if !ok {
+			return fail("expected must be a number")
+		}
+		got, ok := actual.(float64)
+		if !ok {
+			return fail("actual value is not a number")
+		}
+		var passed bool
+		switch op {
+		case AssertOpGt:
+			passed = got > want
+		case AssertOpGte:
+			passed = got >= want
+		case AssertOpLt:
+			passed = got < want
+		case AssertOpLte:
+			passed = got <= want
+		}
+		if !passed {
+			return fail("")
+		}
+	case AssertOpLength:
+		want, ok := expected.(float64)
+		if !ok {
+			return fail("expected must be a number")
+		}
+		var got int
+		switch v := actual.(type) {
+		case string:
+			got = utf8.RuneCountInString(v)
+		case []any:
+			got = len(v)
+		case map[string]any:
+			got = len(v)
+		default:
+			return fail("actual value has no length")
+		}
+		if float64(got) != want {
+			return fail(fmt.Sprintf("length %v", got))
+		}
+	case AssertOpExists:
+		if actual == nil {
+			return fail("")
+		}
+	case AssertOpNotExists:
+		if actual != nil {
+			return fail("")
+		}
+	case AssertOpType:
+		if got := jsonTypeName(actual); got != expected {
+			return fail(fmt.Sprintf("type %v", got))
+		}
+	case AssertOpOneOf:
+		options, ok := expected.([]any)
+		if !ok {
+			return fail("expected must be a list")
+		}
+		for _, opt := range options {
+			if cmp.Equal(opt, actual) {
+				return nil
+			}
+		}
+		return fail("")
+	default:
+		return fail(fmt.Sprintf("unknown operator, try one of [%v]", strings.Join(AssertOpNames(), ", ")))
+	}
+
+	return nil
+}
+
+func containsValue(actual any, expected any) (bool, error) {
+	switch v := actual.(type) {
+	case string:
+		sub, ok := expected.(string)
+		if !ok {
+			return false, fmt.Errorf("expected must be a string when checking a string")
+		}
+		return strings.Contains(v, sub), nil
+	case []any:
+		for _, elem := range v {
+			if cmp.Equal(elem, expected) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		key, ok := expected.(string)
+		if !ok {
+			return false, fmt.Errorf("expected must be a string key when checking an object")
+		}
+		_, found := v[key]
+		return found, nil
+	default:
+		return false, fmt.Errorf("actual value is not a string, array or object")
+	}
+}
+
+// normalizeValue converts values into the same shapes produced by decoding JSON, so that values read from
+// yaml (ints, typed slices, etc) compare equal to those pulled out of a response body
+func normalizeValue(val any) any {
+	if val == nil {
+		return nil
+	}
+	switch reflect.ValueOf(val).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Slice, reflect.Map:
+		valBytes, err := json.Marshal(val)
+		if err != nil {
+			return val
+		}
+		var out any
+		if err := json.Unmarshal(valBytes, &out); err != nil {
+			return val
+		}
+		return out
+	}
+	return val
+}
+
+func jsonTypeName(val any) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return reflect.TypeOf(val).String()
+	}
+}
+
+func formatValue(val any) string {
+	if val == nil {
+		return "null"
+	}
+	valBytes, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+	return string(valBytes)
+}
+
+// assertDiffs renders the diffs of any failed equality assertions within err, prefixed by the call and
+// jq expression they belong to
+func assertDiffs(err error) string {
+	var assErrs *AssertionsError
+	if !errors.As(err, &assErrs) {
+		return ""
+	}
+	var b strings.Builder
+	for _, failure := range assErrs.Failures {
+		if failure.Diff != "" {
+			fmt.Fprintf(&b, "%v: %v (-expected +actual):\n%v", assErrs.Call, failure.JQ, failure.Diff)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateAssert(t *testing.T) {
+	body := map[string]any{
+		"name":   "widget-1234",
+		"count":  float64(3),
+		"tags":   []any{"a", "b"},
+		"nested": map[string]any{"id": float64(7)},
+		"empty":  nil,
+	}
+
+	testData := []struct {
+		name     string
+		op       AssertOp
+		actual   any
+		expected any
+		pass     bool
+	}{
+		{name: "equal default", actual: body["count"], expected: 3, pass: true},
+		{name: "equal nested", op: AssertOpEqual, actual: body["nested"], expected: map[string]any{"id": 7}, pass: true},
+		{name: "equal mismatch", op: AssertOpEqual, actual: body["name"], expected: "widget", pass: false},
+		{name: "not-equal", op: AssertOpNotEqual, actual: body["name"], expected: "widget", pass: true},
+		{name: "not-equal mismatch", op: AssertOpNotEqual, actual: body["count"], expected: 3, pass: false},
+		{name: "contains substring", op: AssertOpContains, actual: body["name"], expected: "1234", pass: true},
+		{name: "contains element", op: AssertOpContains, actual: body["tags"], expected: "b", pass: true},
+		{name: "contains key", op: AssertOpContains, actual: body["nested"], expected: "id", pass: true},
+		{name: "contains missing", op: AssertOpContains, actual: body["tags"], expected: "c", pass: false},
+		{name: "matches", op: AssertOpMatches, actual: body["name"], expected: `^widget-\d+$`, pass: true},
+		{name: "matches mismatch", op: AssertOpMatches, actual: body["name"], expected: `^gadget`, pass: false},
+		{name: "gt", op: AssertOpGt, actual: body["count"], expected: 2, pass: true},
+		{name: "gt equal", op: AssertOpGt, actual: body["count"], expected: 3, pass: false},
+		{name: "gte", op: AssertOpGte, actual: body["count"], expected: 3, pass: true},
+		{name: "lt", op: AssertOpLt, actual: body["count"], expected: 3.5, pass: true},
+		{name: "lte", op: AssertOpLte, actual: body["count"], expected: 2, pass: false},
+		{name: "gt non-number", op: AssertOpGt, actual: body["name"], expected: 2, pass: false},
+		{name: "length array", op: AssertOpLength, actual: body["tags"], expected: 2, pass: true},
+		{name: "length string", op: AssertOpLength, actual: body["name"], expected: 4, pass: false},
+		{name: "exists", op: AssertOpExists, actual: body["name"], pass: true},
+		{name: "exists null", op: AssertOpExists, actual: body["empty"], pass: false},
+		{name: "not-exists", op: AssertOpNotExists, actual: body["missing"], pass: true},
+		{name: "type", op: AssertOpType, actual: body["tags"], expected: "array", pass: true},
+		{name: "type mismatch", op: AssertOpType, actual: body["count"], expected: "string", pass: false},
+		{name: "one-of", op: AssertOpOneOf, actual: body["count"], expected: []any{1, 3}, pass: true},
+		{name: "one-of mismatch", op: AssertOpOneOf, actual: body["name"], expected: []any{"a", "b"}, pass: false},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			err := evaluateAssert(Assert{JQ: ".field", Op: tc.op, Expected: tc.expected}, tc.actual)
+			if tc.pass {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrAssertFailed)
+			}
+		})
+	}
+
+	t.Run("failure message", func(t *testing.T) {
+		err := evaluateAssert(Assert{JQ: ".count", Op: AssertOpGt, Expected: 5}, float64(3))
+		require.EqualError(t, err, "failed assert: .count gt: expected 5, got 3")
+	})
+}
@@ -1,11 +1,52 @@
 package internal
 
+import (
+	"context"
+	"strings"
+	"time"
+)
+
 type ExecuteResult struct {
 	StatusCode int
-	Body       map[string]any
+	Headers    map[string][]string
+	Trailers   map[string][]string
+	Duration   time.Duration
+	Body       any
+	RawBody    []byte
 	Error      error
 }
 
+// JQInput returns the value that jq expressions should be run against for the given input
+func (e *ExecuteResult) JQInput(input JQInput) any {
+	if input != JQInputResponse {
+		return e.Body
+	}
+
+	return map[string]any{
+		"status":      e.StatusCode,
+		"headers":     metadataToJQ(e.Headers),
+		"trailers":    metadataToJQ(e.Trailers),
+		"duration_ms": e.Duration.Milliseconds(),
+		"body":        e.Body,
+	}
+}
+
+// metadataToJQ converts http headers or grpc metadata into a form usable by jq, with lower cased keys so
+// they can be referenced identically regardless of protocol
+func metadataToJQ(md map[string][]string) map[string]any {
+	out := make(map[string]any, len(md))
+	for key, vals := range md {
+		key = strings.ToLower(key)
+		existing, _ := out[key].([]any)
+		for _, val := range vals {
+			existing = append(existing, val)
+		}
+		out[key] = existing
+	}
+	return out
+}
+
 type Executor interface {
-	Execute(call Call) (*ExecuteResult, error)
+	// Execute executes the call, giving up once ctx is done
+	Execute(ctx context.Context, call Call) (*ExecuteResult, error)
 }
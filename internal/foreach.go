@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrForeachSource = errors.New("foreach requires exactly one of var, jq or file")
+	ErrForeachList   = errors.New("foreach requires a list")
+)
+
+// foreachItems returns the items a foreach iterates over. The foreach is expanded as a template first,
+// except for its name, which is expanded for each item
+func (s *sequenceRun) foreachItems(foreach Foreach, seqPath string, params map[string]any) ([]any, error) {
+	if foreach.sourceCount() != 1 {
+		return nil, ErrForeachSource
+	}
+
+	data := s.templateData(params)
+	foreach.Name = ""
+	var expanded Foreach
+	if err := s.renderTemplate(foreach, &expanded, seqPath, data); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case expanded.Var != "":
+		value, ok := data[expanded.Var]
+		if !ok {
+			return nil, fmt.Errorf("variable %v is not set", expanded.Var)
+		}
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%w, variable %v is %T", ErrForeachList, expanded.Var, value)
+		}
+		return items, nil
+	case expanded.JQ != "":
+		value, err := s.executeJQ(data, expanded.JQ)
+		if err != nil {
+			return nil, fmt.Errorf("error executing jq: %w", err)
+		}
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%w, jq %v produced %T", ErrForeachList, expanded.JQ, value)
+		}
+		return normalizeNumbers(items).([]any), nil
+	default:
+		return readForeachFile(s.resolvePath(seqPath, expanded.File))
+	}
+}
+
+// foreachLabel names an iteration of a foreach in reports
+func (s *sequenceRun) foreachLabel(foreach Foreach, seqPath string, idx int, params map[string]any) (string, error) {
+	if foreach.Name == "" {
+		return fmt.Sprint(idx), nil
+	}
+	var label string
+	if err := s.renderTemplate(foreach.Name, &label, seqPath, s.templateData(params)); err != nil {
+		return "", fmt.Errorf("error evaluating foreach name: %w", err)
+	}
+	return label, nil
+}
+
+func (f *Foreach) sourceCount() int {
+	count := 0
+	for _, isSet := range []bool{f.Var != "", f.JQ != "", f.File != ""} {
+		if isSet {
+			count++
+		}
+	}
+	return count
+}
+
+// readForeachFile reads the items of a foreach from a file. Each row of a CSV file is an item, keyed by
+// the headers in the first row, and a JSON file must hold a list, whose whole numbers are kept as such
+func readForeachFile(path string) ([]any, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading foreach file: %w", err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %v as CSV: %w", path, err)
+		}
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		items := make([]any, 0, len(rows)-1)
+		for _, row := range rows[1:] {
+			item := make(map[string]any, len(row))
+			for i, header := range rows[0] {
+				item[header] = row[i]
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case ".json":
+		var value any
+		if err := json.NewDecoder(file).Decode(&value); err != nil {
+			return nil, fmt.Errorf("error reading %v as JSON: %w", path, err)
+		}
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%w, %v holds %T", ErrForeachList, path, value)
+		}
+		return normalizeNumbers(items).([]any), nil
+	default:
+		return nil, fmt.Errorf("unsupported foreach file %v, must be .csv or .json", path)
+	}
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"io"
+	"sync"
+)
+
+// groupBuffer holds writes destined for multiple writers, preserving their relative order, so they can
+// be written out together later. Each write is kept as its own entry since zerolog writers expect to
+// receive a single event per call
+type groupBuffer struct {
+	mu      sync.Mutex
+	entries []groupEntry
+}
+
+type groupEntry struct {
+	dst  io.Writer
+	data []byte
+}
+
+func (g *groupBuffer) writerFor(dst io.Writer) io.Writer {
+	return &groupBufferWriter{group: g, dst: dst}
+}
+
+func (g *groupBuffer) flush() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, entry := range g.entries {
+		if _, err := entry.dst.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	g.entries = nil
+	return nil
+}
+
+type groupBufferWriter struct {
+	group *groupBuffer
+	dst   io.Writer
+}
+
+func (w *groupBufferWriter) Write(p []byte) (int, error) {
+	w.group.mu.Lock()
+	defer w.group.mu.Unlock()
+
+	data := make([]byte, len(p))
+	copy(data, p)
+	w.group.entries = append(w.group.entries, groupEntry{dst: w.dst, data: data})
+	return len(p), nil
+}
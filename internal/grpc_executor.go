@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
@@ -16,20 +22,25 @@ import (
 	"google.golang.org/grpc/metadata"
 	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"google.golang.org/grpc/status"
-	"crypto/tls"
-	"crypto/x509"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 type GRPCExecutorOpts struct {
 	Logger zerolog.Logger
+	// DialTimeout bounds how long establishing a connection may take, defaults to 10 seconds
+	DialTimeout time.Duration
 }
 
 func NewGRPCExecutor(opts GRPCExecutorOpts) *GRPCExecutor {
+	dialTimeout := opts.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 10 * time.Second
+	}
 	return &GRPCExecutor{
 		log:         opts.Logger,
-		descriptors: make(map[string]grpcurl.DescriptorSource),
+		dialTimeout: dialTimeout,
+		descriptors: make(map[string]cachedDescriptors),
 		connections: make(map[string]*grpc.ClientConn),
 	}
 }
@@ -38,27 +49,75 @@ var _ Executor = (*GRPCExecutor)(nil)
 
 type GRPCExecutor struct {
 	log         zerolog.Logger
-	descriptors map[string]grpcurl.DescriptorSource
+	dialTimeout time.Duration
+	descMu      sync.Mutex
+	descriptors map[string]cachedDescriptors
+	connMu      sync.Mutex
 	connections map[string]*grpc.ClientConn
 }
 
-func (g *GRPCExecutor) fetchDescriptors(service string, host string, dialInsecure bool) (grpcurl.DescriptorSource, error) {
-	ds, ok := g.descriptors[service]
-	if ok {
+// cachedDescriptors is a previously loaded source of descriptors. Those fetched using reflection are only
+// usable while the context they were fetched with is live, others have no context
+type cachedDescriptors struct {
+	source grpcurl.DescriptorSource
+	ctx    context.Context
+}
+
+func (g *GRPCExecutor) fetchDescriptors(ctx context.Context, call Call) (grpcurl.DescriptorSource, error) {
+	g.descMu.Lock()
+	defer g.descMu.Unlock()
+
+	service := g.callToServiceName(call)
+	key := g.connectionKey(call) + ";service=" + service
+	if call.DescriptorSource.IsLocal() {
+		key = call.DescriptorSource.cacheKey()
+	}
+
+	cached, ok := g.descriptors[key]
+	if ok && (cached.ctx == nil || cached.ctx.Err() == nil) {
 		g.log.Debug().Str("service", service).Msg("descriptor already fetched, using cache")
-		return ds, nil
+		return cached.source, nil
 	}
 
-	conn, err := g.connection(host, dialInsecure)
-	if err != nil {
-		return nil, err
+	var source grpcurl.DescriptorSource
+	var sourceCtx context.Context
+	switch {
+	case len(call.Protosets) > 0:
+		g.log.Debug().Strs("protosets", call.Protosets).Msg("loading descriptors from protosets")
+		var err error
+		source, err = grpcurl.DescriptorSourceFromProtoSets(call.Protosets...)
+		if err != nil {
+			return nil, fmt.Errorf("error loading protosets: %w", err)
+		}
+	case len(call.ProtoFiles) > 0:
+		g.log.Debug().Strs("proto-files", call.ProtoFiles).Msg("loading descriptors from proto files")
+		importPaths, protoFiles := call.ImportPaths, call.ProtoFiles
+		if len(importPaths) == 0 {
+			// Without import paths, the parser can't deal with absolute paths, so make each file's own
+			// directory an import path instead
+			protoFiles = make([]string, 0, len(call.ProtoFiles))
+			for _, file := range call.ProtoFiles {
+				importPaths = append(importPaths, filepath.Dir(file))
+				protoFiles = append(protoFiles, filepath.Base(file))
+			}
+		}
+		var err error
+		source, err = grpcurl.DescriptorSourceFromProtoFiles(importPaths, protoFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing proto files: %w", err)
+		}
+	default:
+		conn, err := g.connection(ctx, call)
+		if err != nil {
+			return nil, err
+		}
+		g.log.Debug().Msg("fetching descriptors using reflection")
+		client := grpcreflect.NewClientV1Alpha(ctx, reflectpb.NewServerReflectionClient(conn))
+		source = grpcurl.DescriptorSourceFromServer(ctx, client)
+		sourceCtx = ctx
 	}
-	g.log.Debug().Msg("fetching descriptors using reflection")
-	ctx := context.Background()
-	client := grpcreflect.NewClientV1Alpha(ctx, reflectpb.NewServerReflectionClient(conn))
-	source := grpcurl.DescriptorSourceFromServer(ctx, client)
 
-	g.descriptors[service] = source
+	g.descriptors[key] = cachedDescriptors{source: source, ctx: sourceCtx}
 	return source, nil
 }
 
@@ -66,52 +125,75 @@ func (g *GRPCExecutor) callToServiceName(call Call) string {
 	return strings.Split(call.Url, "/")[0]
 }
 
-func (g *GRPCExecutor) connection(host string, dialInsecure bool) (*grpc.ClientConn, error) {
-	conn, ok := g.connections[host]
+// connectionKey identifies everything that goes into dialing a connection, so calls only share a
+// connection when they would have dialed it identically
+func (g *GRPCExecutor) connectionKey(call Call) string {
+	key := fmt.Sprintf("host=%v;plaintext=%v;skip-verify=%v;authority=%v", call.ServiceHost, call.Plaintext, call.SkipVerify, call.Authority)
+	if call.TLS != nil {
+		key += fmt.Sprintf(";tls=%+v", *call.TLS)
+	}
+	return key
+}
+
+func (g *GRPCExecutor) transportCredentials(call Call) (credentials.TransportCredentials, error) {
+	if call.Plaintext {
+		return insecure.NewCredentials(), nil
+	}
+	tlsConf, err := buildTLSConfig(call.TLS, call.SkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(tlsConf), nil
+}
+
+func (g *GRPCExecutor) connection(ctx context.Context, call Call) (*grpc.ClientConn, error) {
+	g.connMu.Lock()
+	defer g.connMu.Unlock()
+
+	host := call.ServiceHost
+	key := g.connectionKey(call)
+	conn, ok := g.connections[key]
 	if ok {
 		g.log.Debug().Str("host", host).Msg("reusing existing connection")
 		return conn, nil
 	}
 
-	certPool, err := x509.SystemCertPool()
+	creds, err := g.transportCredentials(call)
 	if err != nil {
-		g.log.Err(err).Msg("error getting SSL pool")
+		g.log.Err(err).Msg("error building transport credentials")
 		return nil, err
 	}
-	creds := credentials.NewTLS(&tls.Config{RootCAs: certPool})
-	if dialInsecure {
-		creds = insecure.NewCredentials()
+
+	var opts []grpc.DialOption
+	if call.Authority != "" {
+		opts = append(opts, grpc.WithAuthority(call.Authority))
 	}
 
 	g.log.Debug().Str("host", host).Msg("acquiring connection")
-	// TODO: header support
-	ctx := metadata.NewOutgoingContext(context.Background(), grpcurl.MetadataFromHeaders([]string{}))
-	conn, err = grpcurl.BlockingDial(ctx, "tcp", host, creds)
+	dialCtx, cancel := context.WithTimeout(ctx, g.dialTimeout)
+	defer cancel()
+	conn, err = grpcurl.BlockingDial(dialCtx, "tcp", host, creds, opts...)
 	if err != nil {
 		g.log.Err(err).Msg("error dialing service")
 		return nil, err
 	}
 
-	g.connections[host] = conn
+	g.connections[key] = conn
 	return conn, nil
 }
 
-func (g *GRPCExecutor) executeRPC(call Call) (map[string]any, codes.Code, error) {
+func (g *GRPCExecutor) executeRPC(ctx context.Context, call Call, result *ExecuteResult) error {
 	g.log.Debug().Msg("fetching descriptors")
-	descriptor, err := g.fetchDescriptors(g.callToServiceName(call), call.ServiceHost, call.SkipVerify)
+	descriptor, err := g.fetchDescriptors(ctx, call)
 	if err != nil {
 		g.log.Err(err).Msg("error fetching descriptor")
-		return nil, 0, err
+		return err
 	}
 
-	var input io.Reader = strings.NewReader("")
-	if call.Body != nil {
-		bodyBytes, err := json.Marshal(call.Body)
-		if err != nil {
-			g.log.Err(err).Msg("error marshalling request body")
-			return nil, 0, err
-		}
-		input = bytes.NewReader(bodyBytes)
+	input, err := g.requestInput(call)
+	if err != nil {
+		g.log.Err(err).Msg("error marshalling request body")
+		return err
 	}
 
 	g.log.Debug().Msg("building parser and formatter")
@@ -127,63 +209,181 @@ func (g *GRPCExecutor) executeRPC(call Call) (map[string]any, codes.Code, error)
 	)
 	if err != nil {
 		g.log.Err(err).Msg("error getting request formatter & parser")
-		return nil, 0, err
+		return err
 	}
 
 	outBytes := &bytes.Buffer{}
 
-	handler := &grpcurl.DefaultEventHandler{
-		Out:            outBytes,
-		Formatter:      reqFormatter,
-		VerbosityLevel: 0,
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if call.StreamTimeout > 0 {
+		streamCtx, cancel = context.WithTimeout(streamCtx, call.StreamTimeout)
+		defer cancel()
+	}
+
+	handler := &capturingHandler{
+		DefaultEventHandler: &grpcurl.DefaultEventHandler{
+			Out:            outBytes,
+			Formatter:      reqFormatter,
+			VerbosityLevel: 0,
+		},
+		maxMessages: call.MaxMessages,
+		cancel:      cancel,
 	}
 
-	ctx := context.Background()
-	conn, err := g.connection(call.ServiceHost, call.SkipVerify)
+	conn, err := g.connection(ctx, call)
 	if err != nil {
 		g.log.Err(err).Msg("error dialing service")
-		return nil, 0, err
+		return err
 	}
 
 	headers := g.makeRequestHeaderList(call)
-	err = grpcurl.InvokeRPC(ctx, descriptor, conn, call.Url, headers, handler, reqParser.Next)
+	start := time.Now()
+	err = grpcurl.InvokeRPC(streamCtx, descriptor, conn, call.Url, headers, handler, reqParser.Next)
+	result.Duration = time.Since(start)
+	result.Headers = handler.headers
+	result.Trailers = handler.trailers
 	if err != nil {
 		g.log.Err(err).Msg("error invoking RPC")
-		return nil, status.Convert(err).Code(), err
+		result.StatusCode = int(status.Convert(err).Code())
+		return err
 	}
 
-	if handler.Status.Code() != codes.OK {
-		return nil, handler.Status.Code(), handler.Status.Err()
+	// The stream is only ended early by its own limits, not by the call as a whole being given up on
+	if handler.Status.Code() != codes.OK && (ctx.Err() != nil || !handler.endedEarly(streamCtx)) {
+		result.StatusCode = int(handler.Status.Code())
+		return handler.Status.Err()
 	}
 
+	result.RawBody = outBytes.Bytes()
+
 	g.log.Debug().Msg("decoding body")
-	var outBody map[string]any
-	err = json.NewDecoder(outBytes).Decode(&outBody)
-	if err != nil {
-		if err != io.EOF {
+	var messages []any
+	decoder := json.NewDecoder(bytes.NewReader(result.RawBody))
+	for {
+		var msg any
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				break
+			}
 			g.log.Err(err).Msg("error unmarshalling body")
-			return nil, 0, err
+			return err
+		}
+		messages = append(messages, msg)
+	}
+
+	// Streamed responses are always an array, regardless of how many messages were received, so jq
+	// expressions don't need to care how many arrived
+	if handler.serverStreaming {
+		if messages == nil {
+			messages = []any{}
 		}
-		// No body, move along
+		result.Body = messages
+	} else if len(messages) > 0 {
+		result.Body = messages[0]
 	}
 
-	return outBody, codes.OK, nil
+	result.StatusCode = int(codes.OK)
+	return nil
+}
+
+// requestInput encodes the body of the call as a stream of JSON messages. A list body is sent as one
+// message per element, for client & bidirectional streaming methods
+func (g *GRPCExecutor) requestInput(call Call) (io.Reader, error) {
+	if call.Body == nil {
+		return strings.NewReader(""), nil
+	}
+
+	messages, ok := call.Body.([]any)
+	if !ok {
+		messages = []any{call.Body}
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, msg := range messages {
+		if err := encoder.Encode(msg); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
 }
 
 func (g *GRPCExecutor) makeRequestHeaderList(call Call) []string {
 	headers := []string{}
+	for key, val := range call.Metadata {
+		if _, ok := call.Headers[key]; ok {
+			continue
+		}
+		headers = append(headers, fmt.Sprintf("%v: %v", key, val))
+	}
 	for key, val := range call.Headers {
 		headers = append(headers, fmt.Sprintf("%v: %v", key, val))
 	}
 	return headers
 }
 
-func (g *GRPCExecutor) Execute(call Call) (*ExecuteResult, error) {
-	body, code, err := g.executeRPC(call)
+func (g *GRPCExecutor) Execute(ctx context.Context, call Call) (*ExecuteResult, error) {
+	result := &ExecuteResult{}
+	if err := g.executeRPC(ctx, call, result); err != nil {
+		// Without a status, the call failed before the server could respond, such as while dialing or
+		// loading descriptors, so there's no result to check
+		if result.StatusCode == int(codes.OK) {
+			return nil, err
+		}
+		result.Error = err
+	}
+
+	return result, nil
+}
+
+// capturingHandler records the response metadata that the default handler would otherwise only print, and
+// ends streams early once enough messages have been received
+type capturingHandler struct {
+	*grpcurl.DefaultEventHandler
+	headers         metadata.MD
+	trailers        metadata.MD
+	serverStreaming bool
+	maxMessages     int
+	cancel          context.CancelFunc
+	stopped         bool
+}
+
+func (c *capturingHandler) OnResolveMethod(md *desc.MethodDescriptor) {
+	c.serverStreaming = md.IsServerStreaming()
+	c.DefaultEventHandler.OnResolveMethod(md)
+}
+
+func (c *capturingHandler) OnReceiveResponse(resp proto.Message) {
+	c.DefaultEventHandler.OnReceiveResponse(resp)
+	if c.serverStreaming && c.maxMessages > 0 && c.NumResponses >= c.maxMessages {
+		c.stopped = true
+		c.cancel()
+	}
+}
+
+// endedEarly reports if a streaming call was cut short on purpose, by either receiving the maximum number of
+// messages or hitting the stream timeout, rather than failing
+func (c *capturingHandler) endedEarly(ctx context.Context) bool {
+	if !c.serverStreaming {
+		return false
+	}
+	switch c.Status.Code() {
+	case codes.Canceled:
+		return c.stopped
+	case codes.DeadlineExceeded:
+		return errors.Is(ctx.Err(), context.DeadlineExceeded)
+	default:
+		return false
+	}
+}
+
+func (c *capturingHandler) OnReceiveHeaders(md metadata.MD) {
+	c.headers = md
+	c.DefaultEventHandler.OnReceiveHeaders(md)
+}
 
-	return &ExecuteResult{
-		StatusCode: int(code),
-		Body: body,
-		Error: err,
-	}, nil
+func (c *capturingHandler) OnReceiveTrailers(stat *status.Status, md metadata.MD) {
+	c.trailers = md
+	c.DefaultEventHandler.OnReceiveTrailers(stat, md)
 }
@@ -0,0 +1,287 @@
+package internal
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func startGRPCServer(t *testing.T, withReflection bool) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	if withReflection {
+		reflection.Register(server)
+	}
+
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String()
+}
+
+func TestGRPCExecute(t *testing.T) {
+	host := startGRPCServer(t, true)
+
+	t.Run("unary", func(t *testing.T) {
+		ex := NewGRPCExecutor(GRPCExecutorOpts{})
+
+		got, err := ex.Execute(context.Background(), Call{
+			Type:              RequestTypeGrpc,
+			ServiceHost:       host,
+			ConnectionOptions: ConnectionOptions{Plaintext: true},
+			Url:               "grpc.health.v1.Health/Check",
+			Body:              map[string]any{},
+		})
+		require.NoError(t, err)
+		require.NoError(t, got.Error)
+		require.Equal(t, 0, got.StatusCode)
+		require.Equal(t, map[string]any{"status": "SERVING"}, got.Body)
+	})
+
+	t.Run("server stream max messages", func(t *testing.T) {
+		ex := NewGRPCExecutor(GRPCExecutorOpts{})
+
+		got, err := ex.Execute(context.Background(), Call{
+			Type:              RequestTypeGrpc,
+			ServiceHost:       host,
+			ConnectionOptions: ConnectionOptions{Plaintext: true},
+			Url:               "grpc.health.v1.Health/Watch",
+			Body:              map[string]any{},
+			MaxMessages:       1,
+		})
+		require.NoError(t, err)
+		require.NoError(t, got.Error)
+		require.Equal(t, 0, got.StatusCode)
+		require.Equal(t, []any{map[string]any{"status": "SERVING"}}, got.Body)
+	})
+
+	t.Run("server stream timeout", func(t *testing.T) {
+		ex := NewGRPCExecutor(GRPCExecutorOpts{})
+
+		got, err := ex.Execute(context.Background(), Call{
+			Type:              RequestTypeGrpc,
+			ServiceHost:       host,
+			ConnectionOptions: ConnectionOptions{Plaintext: true},
+			Url:               "grpc.health.v1.Health/Watch",
+			Body:              map[string]any{},
+			StreamTimeout:     100 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		require.NoError(t, got.Error)
+		require.Equal(t, []any{map[string]any{"status": "SERVING"}}, got.Body)
+	})
+
+	t.Run("bidi stream", func(t *testing.T) {
+		ex := NewGRPCExecutor(GRPCExecutorOpts{})
+
+		got, err := ex.Execute(context.Background(), Call{
+			Type:              RequestTypeGrpc,
+			ServiceHost:       host,
+			ConnectionOptions: ConnectionOptions{Plaintext: true},
+			Url:               "grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+			Body: []any{
+				map[string]any{"listServices": ""},
+				map[string]any{"fileContainingSymbol": "grpc.health.v1.Health"},
+			},
+		})
+		require.NoError(t, err)
+		require.NoError(t, got.Error)
+
+		messages, ok := got.Body.([]any)
+		require.True(t, ok)
+		require.Len(t, messages, 2)
+		require.Contains(t, messages[0], "listServicesResponse")
+		require.Contains(t, messages[1], "fileDescriptorResponse")
+	})
+}
+
+func TestGRPCDescriptorSources(t *testing.T) {
+	host := startGRPCServer(t, false)
+
+	check := func(t *testing.T, source DescriptorSource) {
+		t.Helper()
+		ex := NewGRPCExecutor(GRPCExecutorOpts{})
+
+		got, err := ex.Execute(context.Background(), Call{
+			Type:              RequestTypeGrpc,
+			ServiceHost:       host,
+			ConnectionOptions: ConnectionOptions{Plaintext: true},
+			Url:               "grpc.health.v1.Health/Check",
+			Body:              map[string]any{},
+			DescriptorSource:  source,
+		})
+		require.NoError(t, err)
+		require.NoError(t, got.Error)
+		require.Equal(t, map[string]any{"status": "SERVING"}, got.Body)
+	}
+
+	t.Run("proto files", func(t *testing.T) {
+		check(t, DescriptorSource{
+			ImportPaths: []string{"testdata/grpc"},
+			ProtoFiles:  []string{"health.proto"},
+		})
+	})
+
+	t.Run("proto files absolute", func(t *testing.T) {
+		path, err := filepath.Abs("testdata/grpc/health.proto")
+		require.NoError(t, err)
+		check(t, DescriptorSource{
+			ProtoFiles: []string{path},
+		})
+	})
+
+	t.Run("protoset", func(t *testing.T) {
+		set := &descriptorpb.FileDescriptorSet{
+			File: []*descriptorpb.FileDescriptorProto{
+				protodesc.ToFileDescriptorProto(healthpb.File_grpc_health_v1_health_proto),
+			},
+		}
+		setBytes, err := proto.Marshal(set)
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "health.protoset")
+		require.NoError(t, os.WriteFile(path, setBytes, 0644))
+
+		check(t, DescriptorSource{
+			Protosets: []string{path},
+		})
+	})
+
+	t.Run("reflection unavailable", func(t *testing.T) {
+		ex := NewGRPCExecutor(GRPCExecutorOpts{})
+
+		got, err := ex.Execute(context.Background(), Call{
+			Type:              RequestTypeGrpc,
+			ServiceHost:       host,
+			ConnectionOptions: ConnectionOptions{Plaintext: true},
+			Url:               "grpc.health.v1.Health/Check",
+			Body:              map[string]any{},
+		})
+		require.NoError(t, err)
+		require.Error(t, got.Error)
+	})
+}
+
+func TestGRPCConnectionOptions(t *testing.T) {
+	pki := newTestPKI(t, "poke.test")
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var (
+		mdMu     sync.Mutex
+		received metadata.MD
+	)
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(pki.ServerTLS)),
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			mdMu.Lock()
+			received, _ = metadata.FromIncomingContext(ctx)
+			mdMu.Unlock()
+			return handler(ctx, req)
+		}),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+	host := lis.Addr().String()
+
+	mtls := &TLSConfig{
+		CAFile:     pki.CAFile,
+		CertFile:   pki.ClientCertFile,
+		KeyFile:    pki.ClientKeyFile,
+		ServerName: "poke.test",
+	}
+
+	check := func(ex *GRPCExecutor, opts ConnectionOptions, skipVerify bool) (*ExecuteResult, error) {
+		t.Helper()
+		return ex.Execute(context.Background(), Call{
+			Type:              RequestTypeGrpc,
+			ServiceHost:       host,
+			SkipVerify:        skipVerify,
+			Url:               "grpc.health.v1.Health/Check",
+			Body:              map[string]any{},
+			Headers:           map[string]string{"x-call": "call"},
+			ConnectionOptions: opts,
+		})
+	}
+
+	t.Run("mtls", func(t *testing.T) {
+		got, err := check(NewGRPCExecutor(GRPCExecutorOpts{DialTimeout: time.Second}), ConnectionOptions{TLS: mtls}, false)
+		require.NoError(t, err)
+		require.NoError(t, got.Error)
+		require.Equal(t, map[string]any{"status": "SERVING"}, got.Body)
+	})
+
+	t.Run("authority", func(t *testing.T) {
+		got, err := check(
+			NewGRPCExecutor(GRPCExecutorOpts{DialTimeout: time.Second}),
+			ConnectionOptions{
+				TLS:       &TLSConfig{CAFile: pki.CAFile, CertFile: pki.ClientCertFile, KeyFile: pki.ClientKeyFile},
+				Authority: "poke.test",
+			},
+			false,
+		)
+		require.NoError(t, err)
+		require.NoError(t, got.Error)
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		got, err := check(
+			NewGRPCExecutor(GRPCExecutorOpts{DialTimeout: time.Second}),
+			ConnectionOptions{
+				TLS:      mtls,
+				Metadata: map[string]string{"x-conn": "conn", "x-call": "overridden"},
+			},
+			false,
+		)
+		require.NoError(t, err)
+		require.NoError(t, got.Error)
+
+		mdMu.Lock()
+		defer mdMu.Unlock()
+		require.Equal(t, []string{"conn"}, received.Get("x-conn"))
+		require.Equal(t, []string{"call"}, received.Get("x-call"))
+	})
+
+	t.Run("missing client cert", func(t *testing.T) {
+		_, err := check(NewGRPCExecutor(GRPCExecutorOpts{DialTimeout: time.Second}), ConnectionOptions{TLS: &TLSConfig{CAFile: pki.CAFile, ServerName: "poke.test"}}, false)
+		require.Error(t, err)
+	})
+
+	t.Run("connections not shared across settings", func(t *testing.T) {
+		ex := NewGRPCExecutor(GRPCExecutorOpts{DialTimeout: time.Second})
+
+		got, err := check(ex, ConnectionOptions{TLS: &TLSConfig{CertFile: pki.ClientCertFile, KeyFile: pki.ClientKeyFile}}, true)
+		require.NoError(t, err)
+		require.NoError(t, got.Error)
+
+		// Same host, but without skip-verify or the CA the server can't be trusted
+		_, err = check(ex, ConnectionOptions{TLS: &TLSConfig{CertFile: pki.ClientCertFile, KeyFile: pki.ClientKeyFile}}, false)
+		require.Error(t, err)
+	})
+}
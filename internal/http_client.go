@@ -1,16 +1,32 @@
 package internal
 
 import (
-	"crypto/tls"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 )
 
+// TransportOptions are the per-call settings that determine which transport a request is sent with
+type TransportOptions struct {
+	SkipVerify bool
+	TLS        *TLSConfig
+	Proxy      string
+}
+
+func (t TransportOptions) key() string {
+	key := fmt.Sprintf("skip-verify=%v;proxy=%v", t.SkipVerify, t.Proxy)
+	if t.TLS != nil {
+		key += fmt.Sprintf(";tls=%+v", *t.TLS)
+	}
+	return key
+}
+
 type IHttpClient interface {
-	Do(req *http.Request) (*http.Response, error)
-	SetNoTLSVerify()
+	Do(req *http.Request, opts TransportOptions) (*http.Response, error)
 	SetTimeout(timeout time.Duration)
 }
 
@@ -20,28 +36,69 @@ type HttpClientConfig struct {
 
 func NewHttpClient(conf HttpClientConfig) *HttpClient {
 	return &HttpClient{
-		log: conf.Logger,
-		client: &http.Client{},
+		log:     conf.Logger,
+		clients: make(map[string]*http.Client),
 	}
 }
 
 var _ IHttpClient = (*HttpClient)(nil)
 
+// HttpClient is safe for concurrent use. Requests with different transport options are sent using
+// separate clients, so settings for one call never leak into another
 type HttpClient struct {
-	log    zerolog.Logger
-	client *http.Client
+	log     zerolog.Logger
+	mu      sync.Mutex
+	timeout time.Duration
+	clients map[string]*http.Client
 }
 
-func (h *HttpClient) Do(req *http.Request) (*http.Response, error) {
-	return h.client.Do(req)
+func (h *HttpClient) Do(req *http.Request, opts TransportOptions) (*http.Response, error) {
+	client, err := h.clientFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
 }
 
-func (h *HttpClient) SetNoTLSVerify() {
-	h.client.Transport = &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+func (h *HttpClient) clientFor(opts TransportOptions) (*http.Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := opts.key()
+	if client, ok := h.clients[key]; ok {
+		return client, nil
 	}
+
+	tlsConf, err := buildTLSConfig(opts.TLS, opts.SkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConf
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	h.log.Debug().Str("transport", key).Msg("creating client for transport options")
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   h.timeout,
+	}
+	h.clients[key] = client
+	return client, nil
 }
 
+// SetTimeout sets the timeout for all requests, it should be called before any requests are made
 func (h *HttpClient) SetTimeout(timeout time.Duration) {
-	h.client.Timeout = timeout
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timeout = timeout
+	for _, client := range h.clients {
+		client.Timeout = timeout
+	}
 }
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpClientTransportOptions(t *testing.T) {
+	pki := newTestPKI(t, "poke.test")
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = pki.ServerTLS
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	mtls := &TLSConfig{
+		CAFile:     pki.CAFile,
+		CertFile:   pki.ClientCertFile,
+		KeyFile:    pki.ClientKeyFile,
+		ServerName: "poke.test",
+	}
+
+	do := func(client *HttpClient, opts TransportOptions) error {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req, opts)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	t.Run("mtls", func(t *testing.T) {
+		client := NewHttpClient(HttpClientConfig{})
+		require.NoError(t, do(client, TransportOptions{TLS: mtls}))
+	})
+
+	t.Run("min version", func(t *testing.T) {
+		client := NewHttpClient(HttpClientConfig{})
+		conf := *mtls
+		conf.MinVersion = "1.3"
+		require.NoError(t, do(client, TransportOptions{TLS: &conf}))
+	})
+
+	t.Run("missing client cert", func(t *testing.T) {
+		client := NewHttpClient(HttpClientConfig{})
+		require.Error(t, do(client, TransportOptions{TLS: &TLSConfig{CAFile: pki.CAFile, ServerName: "poke.test"}}))
+	})
+
+	t.Run("settings do not leak between calls", func(t *testing.T) {
+		client := NewHttpClient(HttpClientConfig{})
+		certOnly := &TLSConfig{CertFile: pki.ClientCertFile, KeyFile: pki.ClientKeyFile}
+
+		require.NoError(t, do(client, TransportOptions{SkipVerify: true, TLS: certOnly}))
+		require.Error(t, do(client, TransportOptions{TLS: certOnly}))
+	})
+
+	t.Run("proxy", func(t *testing.T) {
+		proxied := make(chan string, 1)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied <- r.URL.String()
+			w.WriteHeader(http.StatusTeapot)
+		}))
+		t.Cleanup(proxy.Close)
+
+		client := NewHttpClient(HttpClientConfig{})
+		req, err := http.NewRequest(http.MethodGet, "http://some.host.invalid/path", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req, TransportOptions{Proxy: proxy.URL})
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, http.StatusTeapot, resp.StatusCode)
+		require.Equal(t, "http://some.host.invalid/path", <-proxied)
+	})
+}
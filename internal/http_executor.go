@@ -1,15 +1,27 @@
 package internal
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 )
 
+var ErrMultipleBodies = errors.New("only one of body, body-raw, body-form, body-multipart or body-file may be given")
+
 type HTTPExecutorOpts struct {
 	Logger zerolog.Logger
 	Client IHttpClient
@@ -29,58 +41,186 @@ type HTTPExecutor struct {
 	client IHttpClient
 }
 
-func (h *HTTPExecutor) Execute(call Call) (*ExecuteResult, error) {
-	var inBody io.Reader
-	if call.Body != nil {
-		bodyBytes, err := json.Marshal(call.Body)
-		h.log.Debug().Bytes("bodyBytes", bodyBytes).Msg("adding message body")
-		if err != nil {
-			return nil, fmt.Errorf("error marshalling body as JSON: %w", err)
-		}
-		inBody = strings.NewReader(string(bodyBytes))
+func (h *HTTPExecutor) Execute(ctx context.Context, call Call) (*ExecuteResult, error) {
+	inBody, contentType, err := h.buildBody(call)
+	if err != nil {
+		return nil, err
 	}
 
-	method := call.Method
-	if method == "" {
-		if call.Body != nil {
-			method = http.MethodPost
-		} else {
-			method = http.MethodGet
-		}
-	}
+	method := call.GetMethod()
 	h.log.Debug().Str("method", method).Str("url", call.Url).Msg("executing call")
-	req, err := http.NewRequest(method, call.Url, inBody)
+	req, err := http.NewRequestWithContext(ctx, method, call.Url, inBody)
 	if err != nil {
 		return nil, fmt.Errorf("error building request: %w", err)
 	}
 
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 	if call.Headers != nil {
 		for k, v := range call.Headers {
 			req.Header.Set(k, v)
 		}
 	}
 
-	if call.SkipVerify {
-		h.client.SetNoTLSVerify()
-	}
-
-	resp, err := h.client.Do(req)
+	start := time.Now()
+	resp, err := h.client.Do(req, TransportOptions{
+		SkipVerify: call.SkipVerify,
+		TLS:        call.TLS,
+		Proxy:      call.Proxy,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	var outBody map[string]any
-	err = json.NewDecoder(resp.Body).Decode(&outBody)
+	rawBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		if err != io.EOF {
-			return nil, fmt.Errorf("error decoding body as JSON: %w", err)
-		}
-		// No body, move along
+		return nil, fmt.Errorf("error reading body: %w", err)
+	}
+	duration := time.Since(start)
+
+	outBody, err := h.decodeBody(call, resp.Header.Get("Content-Type"), rawBody)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ExecuteResult{
 		Body:       outBody,
+		RawBody:    rawBody,
 		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+		Trailers:   resp.Trailer,
+		Duration:   duration,
 	}, nil
 }
+
+// buildBody builds the request body from whichever body option is set on the call, returning it along
+// with the Content-Type it should be sent with
+func (h *HTTPExecutor) buildBody(call Call) (io.Reader, string, error) {
+	if call.bodyCount() > 1 {
+		return nil, "", ErrMultipleBodies
+	}
+
+	switch {
+	case call.Body != nil:
+		bodyBytes, err := json.Marshal(call.Body)
+		h.log.Debug().Bytes("bodyBytes", bodyBytes).Msg("adding message body")
+		if err != nil {
+			return nil, "", fmt.Errorf("error marshalling body as JSON: %w", err)
+		}
+		return bytes.NewReader(bodyBytes), "application/json", nil
+	case call.BodyRaw != "":
+		h.log.Debug().Str("body", call.BodyRaw).Msg("adding raw message body")
+		return strings.NewReader(call.BodyRaw), "text/plain; charset=utf-8", nil
+	case call.BodyForm != nil:
+		form := url.Values{}
+		for k, v := range call.BodyForm {
+			form.Set(k, v)
+		}
+		h.log.Debug().Str("body", form.Encode()).Msg("adding form body")
+		return strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil
+	case call.BodyMultipart != nil:
+		return h.buildMultipartBody(call.BodyMultipart)
+	case call.BodyFile != "":
+		fileBytes, err := os.ReadFile(call.BodyFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading body file: %w", err)
+		}
+		contentType := mime.TypeByExtension(filepath.Ext(call.BodyFile))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h.log.Debug().Str("file", call.BodyFile).Msg("adding file body")
+		return bytes.NewReader(fileBytes), contentType, nil
+	default:
+		return nil, "", nil
+	}
+}
+
+func (h *HTTPExecutor) buildMultipartBody(parts []MultipartPart) (io.Reader, string, error) {
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+
+	for _, part := range parts {
+		if part.File == "" {
+			if err := writer.WriteField(part.Name, part.Value); err != nil {
+				return nil, "", fmt.Errorf("error writing multipart field %v: %w", part.Name, err)
+			}
+			continue
+		}
+
+		fileBytes, err := os.ReadFile(part.File)
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading multipart file %v: %w", part.Name, err)
+		}
+
+		contentType := part.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%v"; filename="%v"`, part.Name, filepath.Base(part.File)))
+		header.Set("Content-Type", contentType)
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", fmt.Errorf("error creating multipart file %v: %w", part.Name, err)
+		}
+		if _, err := partWriter.Write(fileBytes); err != nil {
+			return nil, "", fmt.Errorf("error writing multipart file %v: %w", part.Name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("error finalizing multipart body: %w", err)
+	}
+
+	return buf, writer.FormDataContentType(), nil
+}
+
+// decodeBody converts the raw response body into a value usable by jq. JSON content is decoded into
+// whatever JSON value it holds, and anything else is kept as a string. Bodies without a Content-Type are
+// decoded as JSON if possible
+func (h *HTTPExecutor) decodeBody(call Call, contentType string, rawBody []byte) (any, error) {
+	if len(rawBody) == 0 {
+		return nil, nil
+	}
+
+	format := call.ResponseFormat
+	if format == "" {
+		switch {
+		case contentType == "":
+			var outBody any
+			if err := json.Unmarshal(rawBody, &outBody); err != nil {
+				h.log.Debug().Msg("body without Content-Type is not JSON, treating as text")
+				return string(rawBody), nil
+			}
+			return outBody, nil
+		case isJSONContentType(contentType):
+			format = ResponseFormatJson
+		default:
+			format = ResponseFormatText
+		}
+	}
+
+	switch format {
+	case ResponseFormatJson:
+		var outBody any
+		if err := json.Unmarshal(rawBody, &outBody); err != nil {
+			return nil, fmt.Errorf("error decoding body as JSON: %w", err)
+		}
+		return outBody, nil
+	case ResponseFormatText:
+		return string(rawBody), nil
+	default:
+		return nil, fmt.Errorf("unhandled response format '%v'", format)
+	}
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -53,8 +54,8 @@ func TestSimpleExecute(t *testing.T) {
 	t.Run("with body", func(t *testing.T) {
 		client := NewMockIHttpClient(t)
 		client.EXPECT().
-			Do(mock.Anything).
-			RunAndReturn(func(r *http.Request) (*http.Response, error) {
+			Do(mock.Anything, mock.Anything).
+			RunAndReturn(func(r *http.Request, _ TransportOptions) (*http.Response, error) {
 				expectBody(t, map[string]any{"foo": "bar"})(r)
 
 				return jsonResponse(
@@ -70,15 +71,19 @@ func TestSimpleExecute(t *testing.T) {
 			Client: client,
 		})
 
-		got, err := ex.Execute(Call{
+		got, err := ex.Execute(context.Background(), Call{
 			Url:  "http://some.host.com/some-endpoint",
 			Body: map[string]any{"foo": "bar"},
 		})
 		require.NoError(t, err)
+		require.Positive(t, got.Duration)
+		got.Duration = 0
 		require.Equal(
 			t,
 			&ExecuteResult{
 				Body:       map[string]any{"baz": "qux"},
+				RawBody:    []byte(`{"baz":"qux"}`),
+				Headers:    map[string][]string{},
 				StatusCode: http.StatusOK,
 			},
 			got,
@@ -88,8 +93,8 @@ func TestSimpleExecute(t *testing.T) {
 	t.Run("no body", func(t *testing.T) {
 		client := NewMockIHttpClient(t)
 		client.EXPECT().
-			Do(mock.Anything).
-			RunAndReturn(func(r *http.Request) (*http.Response, error) {
+			Do(mock.Anything, mock.Anything).
+			RunAndReturn(func(r *http.Request, _ TransportOptions) (*http.Response, error) {
 				expectBody(t, nil)(r)
 				return jsonResponse(t, nil, http.StatusOK), nil
 			})
@@ -97,16 +102,249 @@ func TestSimpleExecute(t *testing.T) {
 			Client: client,
 		})
 
-		got, err := ex.Execute(Call{
+		got, err := ex.Execute(context.Background(), Call{
 			Url:  "http://some.host.com/some-endpoint",
 		})
 		require.NoError(t, err)
+		got.Duration = 0
 		require.Equal(
 			t,
 			&ExecuteResult{
+				RawBody:    []byte{},
+				Headers:    map[string][]string{},
 				StatusCode: http.StatusOK,
 			},
 			got,
 		)
 	})
 }
+
+func TestResponseFormats(t *testing.T) {
+	respond := func(t *testing.T, contentType string, body string) *MockIHttpClient {
+		t.Helper()
+		client := NewMockIHttpClient(t)
+		client.EXPECT().
+			Do(mock.Anything, mock.Anything).
+			Return(&http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(body)),
+				Header:     http.Header{"Content-Type": {contentType}},
+			}, nil)
+		return client
+	}
+
+	testData := []struct {
+		name        string
+		contentType string
+		format      ResponseFormat
+		body        string
+		want        any
+	}{
+		{
+			name:        "json array",
+			contentType: "application/json; charset=utf-8",
+			body:        `[{"id": 1}, {"id": 2}]`,
+			want:        []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2)}},
+		},
+		{
+			name:        "json string",
+			contentType: "application/json",
+			body:        `"hello"`,
+			want:        "hello",
+		},
+		{
+			name:        "json suffix",
+			contentType: "application/problem+json",
+			body:        `{"title": "bad"}`,
+			want:        map[string]any{"title": "bad"},
+		},
+		{
+			name:        "plain text",
+			contentType: "text/plain",
+			body:        "OK",
+			want:        "OK",
+		},
+		{
+			name:        "xml",
+			contentType: "application/xml",
+			body:        "<foo>bar</foo>",
+			want:        "<foo>bar</foo>",
+		},
+		{
+			name: "no content type json",
+			body: `{"foo": "bar"}`,
+			want: map[string]any{"foo": "bar"},
+		},
+		{
+			name: "no content type html",
+			body: "<html></html>",
+			want: "<html></html>",
+		},
+		{
+			name:        "override text",
+			contentType: "application/json",
+			format:      ResponseFormatText,
+			body:        `{"foo": "bar"}`,
+			want:        `{"foo": "bar"}`,
+		},
+		{
+			name:        "override json",
+			contentType: "text/html",
+			format:      ResponseFormatJson,
+			body:        `{"foo": "bar"}`,
+			want:        map[string]any{"foo": "bar"},
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			ex := NewHTTPExecutor(HTTPExecutorOpts{
+				Client: respond(t, tc.contentType, tc.body),
+			})
+
+			got, err := ex.Execute(context.Background(), Call{
+				Url:            "http://some.host.com/some-endpoint",
+				ResponseFormat: tc.format,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got.Body)
+		})
+	}
+
+	t.Run("invalid json errors", func(t *testing.T) {
+		ex := NewHTTPExecutor(HTTPExecutorOpts{
+			Client: respond(t, "application/json", "not json"),
+		})
+
+		_, err := ex.Execute(context.Background(), Call{Url: "http://some.host.com/some-endpoint"})
+		require.ErrorContains(t, err, "error decoding body as JSON")
+	})
+}
+
+func TestRequestBodies(t *testing.T) {
+	capture := func(t *testing.T, check func(*http.Request)) *MockIHttpClient {
+		t.Helper()
+		client := NewMockIHttpClient(t)
+		client.EXPECT().
+			Do(mock.Anything, mock.Anything).
+			RunAndReturn(func(r *http.Request, _ TransportOptions) (*http.Response, error) {
+				check(r)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBuffer(nil)),
+					Header:     make(http.Header),
+				}, nil
+			})
+		return client
+	}
+
+	t.Run("raw", func(t *testing.T) {
+		client := capture(t, func(r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "application/xml", r.Header.Get("Content-Type"))
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.Equal(t, "<foo>bar</foo>", string(body))
+		})
+		ex := NewHTTPExecutor(HTTPExecutorOpts{Client: client})
+
+		_, err := ex.Execute(context.Background(), Call{
+			Url:     "http://some.host.com/some-endpoint",
+			BodyRaw: "<foo>bar</foo>",
+			Headers: map[string]string{"Content-Type": "application/xml"},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("form", func(t *testing.T) {
+		client := capture(t, func(r *http.Request) {
+			require.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "admin", r.PostForm.Get("username"))
+			require.Equal(t, "p@ss word", r.PostForm.Get("password"))
+		})
+		ex := NewHTTPExecutor(HTTPExecutorOpts{Client: client})
+
+		_, err := ex.Execute(context.Background(), Call{
+			Url:      "http://some.host.com/login",
+			BodyForm: map[string]string{"username": "admin", "password": "p@ss word"},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		client := capture(t, func(r *http.Request) {
+			require.NoError(t, r.ParseMultipartForm(1024))
+			require.Equal(t, "a text file", r.MultipartForm.Value["description"][0])
+
+			file := r.MultipartForm.File["upload"][0]
+			require.Equal(t, "upload.txt", file.Filename)
+			require.Equal(t, "text/plain", file.Header.Get("Content-Type"))
+			f, err := file.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(f)
+			require.NoError(t, err)
+			require.Equal(t, "hello from a file\n", string(content))
+		})
+		ex := NewHTTPExecutor(HTTPExecutorOpts{Client: client})
+
+		_, err := ex.Execute(context.Background(), Call{
+			Url: "http://some.host.com/upload",
+			BodyMultipart: []MultipartPart{
+				{Name: "description", Value: "a text file"},
+				{Name: "upload", File: "testdata/http/upload.txt", ContentType: "text/plain"},
+			},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		client := capture(t, func(r *http.Request) {
+			require.Equal(t, "text/xml; charset=utf-8", r.Header.Get("Content-Type"))
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.Equal(t, "<foo>bar</foo>\n", string(body))
+		})
+		ex := NewHTTPExecutor(HTTPExecutorOpts{Client: client})
+
+		_, err := ex.Execute(context.Background(), Call{
+			Url:      "http://some.host.com/upload",
+			Method:   http.MethodPut,
+			BodyFile: "testdata/http/payload.xml",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("transport options", func(t *testing.T) {
+		tlsConf := &TLSConfig{CAFile: "/some/ca.pem", MinVersion: "1.2"}
+		client := NewMockIHttpClient(t)
+		client.EXPECT().
+			Do(mock.Anything, TransportOptions{SkipVerify: true, TLS: tlsConf, Proxy: "http://proxy:3128"}).
+			Return(&http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBuffer(nil)),
+				Header:     make(http.Header),
+			}, nil)
+		ex := NewHTTPExecutor(HTTPExecutorOpts{Client: client})
+
+		_, err := ex.Execute(context.Background(), Call{
+			Url:        "https://some.host.com/",
+			SkipVerify: true,
+			ConnectionOptions: ConnectionOptions{
+				TLS:   tlsConf,
+				Proxy: "http://proxy:3128",
+			},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("multiple bodies error", func(t *testing.T) {
+		ex := NewHTTPExecutor(HTTPExecutorOpts{})
+
+		_, err := ex.Execute(context.Background(), Call{
+			Url:     "http://some.host.com/upload",
+			Body:    map[string]any{"foo": "bar"},
+			BodyRaw: "foo",
+		})
+		require.ErrorContains(t, err, "only one of")
+	})
+}
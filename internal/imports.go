@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrImportCycle      = errors.New("import cycle")
+	ErrUnknownImport    = errors.New("unable to find import")
+	ErrMissingParameter = errors.New("missing required parameter")
+)
+
+// importWith holds the parameters given by a from-import, along with the sequence giving them
+type importWith struct {
+	seq  *Sequence
+	with map[string]any
+}
+
+// resolveImports parses the imports of the sequence, and recursively those of the sequences it imports.
+// chain holds the files importing the sequence, to detect cycles. Problems with every import are
+// reported, rather than just the first
+func (r *Runner) resolveImports(seq *Sequence, chain []string) error {
+	seq.imported = make(map[string]*Sequence, len(seq.Imports))
+	chain = append(chain[:len(chain):len(chain)], filepath.Clean(seq.file))
+
+	var errs []error
+	for _, name := range sortedKeys(seq.Imports) {
+		path := r.resolvePath(seq.path, seq.Imports[name])
+		pos := seq.importPositions[name]
+
+		for _, importer := range chain {
+			if importer == filepath.Clean(path) {
+				errs = append(errs, atPosition(pos, fmt.Errorf("%w: %v", ErrImportCycle, strings.Join(append(chain, filepath.Clean(path)), " -> "))))
+				path = ""
+				break
+			}
+		}
+		if path == "" {
+			continue
+		}
+
+		r.log.Debug().Str("import", name).Str("path", path).Msg("parsing imported sequence")
+		impSeq, err := r.parser.ParseSingleSequence(path)
+		if err != nil {
+			errs = append(errs, atPosition(pos, fmt.Errorf("error parsing imported sequence '%v': %w", seq.Imports[name], err)))
+			continue
+		}
+		if err := r.resolveImports(&impSeq, chain); err != nil {
+			errs = append(errs, fmt.Errorf("error resolving imports of %v: %w", name, err))
+			continue
+		}
+		seq.imported[name] = &impSeq
+	}
+	return errors.Join(errs...)
+}
+
+// resolveImportedCall follows the from-import of a call to the call it refers to, returning it along with
+// the sequence that defines it, and the parameters given along the way, outermost first. If the call
+// refers to an entire imported sequence, the returned call is that reference, which is guaranteed to
+// exist within the returned sequence
+func resolveImportedCall(seq *Sequence, c Call) (Call, *Sequence, []importWith, error) {
+	var withs []importWith
+	for c.FromImport != nil {
+		imp, ok := seq.imported[c.FromImport.Name]
+		if !ok {
+			return Call{}, nil, nil, atPosition(c.pos, fmt.Errorf("%w %v", ErrUnknownImport, c.FromImport.Name))
+		}
+		if len(c.FromImport.With) > 0 {
+			withs = append(withs, importWith{seq: seq, with: c.FromImport.With})
+		}
+		if c.FromImport.Call == "" {
+			break
+		}
+		impCall, ok := imp.callNamed(c.FromImport.Call)
+		if !ok {
+			return Call{}, nil, nil, atPosition(c.pos, fmt.Errorf("unable to find call %v in imported sequence %v", c.FromImport.Call, c.FromImport.Name))
+		}
+		seq, c = imp, impCall
+	}
+	return c, seq, withs, nil
+}
+
+// resolveParams fills in the parameters declared by the call that weren't given. A parameter that
+// isn't given takes the value of the variable of the same name, if there is one, and otherwise its
+// default
+func resolveParams(call Call, given map[string]any, vars map[string]any) (map[string]any, error) {
+	params := make(map[string]any, len(given)+len(call.Params))
+	for k, v := range given {
+		params[k] = v
+	}
+
+	var missing []string
+	for _, name := range sortedKeys(call.Params) {
+		param := call.Params[name]
+		if _, ok := params[name]; ok {
+			continue
+		}
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		if param.Default != nil {
+			params[name] = param.Default
+			continue
+		}
+		if param.Required {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrMissingParameter, strings.Join(missing, ", "))
+	}
+	return params, nil
+}
+
+// importedVars returns the vars of every sequence imported by seq, ordered from lowest to highest
+// precedence. The vars of an import take precedence over those of its own imports, and imports are
+// ordered by name
+func importedVars(seq *Sequence) []map[string]any {
+	var layers []map[string]any
+	for _, name := range sortedKeys(seq.imported) {
+		imp := seq.imported[name]
+		layers = append(layers, importedVars(imp)...)
+		layers = append(layers, imp.Vars)
+	}
+	return layers
+}
+
+func (s *Sequence) callNamed(name string) (Call, bool) {
+	for _, call := range s.Calls {
+		if call.Name == name {
+			return call, true
+		}
+	}
+	return Call{}, false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type JSONReporterOpts struct {
+	Output io.Writer
+}
+
+func NewJSONReporter(opts JSONReporterOpts) *JSONReporter {
+	return &JSONReporter{
+		output: opts.Output,
+		now:    time.Now,
+	}
+}
+
+var _ Reporter = (*JSONReporter)(nil)
+
+// JSONReporter writes a stream of newline delimited JSON events as the run progresses, one as each
+// sequence starts and finishes, and one for each call
+type JSONReporter struct {
+	mu     sync.Mutex
+	output io.Writer
+	now    func() time.Time
+	err    error
+}
+
+const (
+	jsonEventSequenceStart = "sequence-start"
+	jsonEventSequenceEnd   = "sequence-end"
+	jsonEventCall          = "call"
+
+	jsonResultPassed  = "passed"
+	jsonResultFailed  = "failed"
+	jsonResultSkipped = "skipped"
+)
+
+type jsonEvent struct {
+	Event      string         `json:"event"`
+	Time       time.Time      `json:"time"`
+	Sequence   string         `json:"sequence"`
+	File       string         `json:"file,omitempty"`
+	Call       string         `json:"call,omitempty"`
+	Type       RequestType    `json:"type,omitempty"`
+	Url        string         `json:"url,omitempty"`
+	Method     string         `json:"method,omitempty"`
+	Status     *int           `json:"status,omitempty"`
+	DurationMs *int64         `json:"duration_ms,omitempty"`
+	Result     string         `json:"result,omitempty"`
+	Exports    map[string]any `json:"exports,omitempty"`
+	Asserts    []jsonAssert   `json:"asserts,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	// SkipReason is only set on call events
+	SkipReason string `json:"skip_reason,omitempty"`
+	// TeardownError is only set on sequence-end events
+	TeardownError string `json:"teardown_error,omitempty"`
+	// Seed is only set on sequence-start events
+	Seed int64 `json:"seed,omitempty"`
+}
+
+type jsonAssert struct {
+	JQ       string   `json:"jq"`
+	Op       AssertOp `json:"op"`
+	Input    JQInput  `json:"input,omitempty"`
+	Expected any      `json:"expected,omitempty"`
+	Actual   any      `json:"actual,omitempty"`
+	Passed   bool     `json:"passed"`
+	Error    string   `json:"error,omitempty"`
+}
+
+func (j *JSONReporter) SequenceStarted(report SequenceReport) {
+	j.write(jsonEvent{
+		Event:    jsonEventSequenceStart,
+		Sequence: report.Name,
+		File:     report.File,
+		Seed:     report.Seed,
+	})
+}
+
+func (j *JSONReporter) CallFinished(report CallReport) {
+	event := jsonEvent{
+		Event:      jsonEventCall,
+		Sequence:   report.Sequence,
+		Call:       report.Name,
+		DurationMs: jsonMillis(report.Duration),
+		Result:     jsonResult(report.Err, report.Skipped),
+		Exports:    report.Exports,
+		SkipReason: report.SkipReason,
+	}
+	if report.Call != nil {
+		event.Type = report.Call.GetType()
+		event.Url = report.Call.Url
+		if event.Type == RequestTypeHttp {
+			event.Method = report.Call.GetMethod()
+		}
+	}
+	if report.Result != nil {
+		status := report.Result.StatusCode
+		event.Status = &status
+	}
+	for _, ass := range report.Asserts {
+		jsonAss := jsonAssert{
+			JQ:       ass.Assert.JQ,
+			Op:       ass.Assert.GetOp(),
+			Input:    ass.Assert.Input,
+			Expected: ass.Assert.Expected,
+			Actual:   ass.Actual,
+			Passed:   ass.Err == nil,
+		}
+		if ass.Err != nil {
+			jsonAss.Error = ass.Err.Error()
+		}
+		event.Asserts = append(event.Asserts, jsonAss)
+	}
+	if report.Err != nil {
+		event.Error = report.Err.Error()
+	}
+	j.write(event)
+}
+
+func (j *JSONReporter) SequenceFinished(report SequenceReport) {
+	event := jsonEvent{
+		Event:      jsonEventSequenceEnd,
+		Sequence:   report.Name,
+		File:       report.File,
+		DurationMs: jsonMillis(report.Duration),
+		Result:     jsonResult(report.Err, report.Skipped),
+	}
+	if report.Err != nil {
+		event.Error = report.Err.Error()
+	}
+	if report.TeardownErr != nil {
+		event.TeardownError = report.TeardownErr.Error()
+	}
+	j.write(event)
+}
+
+// Close returns the first error encountered writing events, if any
+func (j *JSONReporter) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.err
+}
+
+func (j *JSONReporter) write(event jsonEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.err != nil {
+		return
+	}
+
+	event.Time = j.now()
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		j.err = fmt.Errorf("error marshalling %v event: %w", event.Event, err)
+		return
+	}
+	if _, err := j.output.Write(append(eventBytes, '\n')); err != nil {
+		j.err = fmt.Errorf("error writing json report: %w", err)
+	}
+}
+
+func jsonMillis(d time.Duration) *int64 {
+	ms := d.Milliseconds()
+	return &ms
+}
+
+func jsonResult(err error, skipped bool) string {
+	switch {
+	case skipped:
+		return jsonResultSkipped
+	case err != nil:
+		return jsonResultFailed
+	default:
+		return jsonResultPassed
+	}
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONReporter(t *testing.T) {
+	login := Call{
+		Name: "login",
+		Url:  "http://some.api.com/login",
+		Body: map[string]any{"user": "foo"},
+		Exports: []Export{
+			{JQ: ".token", As: "token"},
+		},
+	}
+	fetch := Call{
+		Name: "fetch",
+		Url:  "http://some.api.com/objects",
+		Asserts: []Assert{
+			{JQ: ".count", Op: AssertOpGt, Expected: 1},
+			{JQ: ".name", Expected: "foo"},
+		},
+	}
+
+	mockParser := NewMockParser(t)
+	mockParser.EXPECT().Parse("./some/path").Return(
+		SequenceMap{"a.yaml": Sequence{file: "root/a.yaml", path: "root", Calls: []Call{login, fetch}}},
+		nil,
+	)
+
+	mockEx := NewMockExecutor(t)
+	mockEx.EXPECT().Execute(mock.Anything, login).Return(&ExecuteResult{
+		StatusCode: 200,
+		Body:       map[string]any{"token": "abc"},
+	}, nil)
+	mockEx.EXPECT().Execute(mock.Anything, fetch).Return(&ExecuteResult{
+		StatusCode: 200,
+		Body:       map[string]any{"count": 2, "name": "bar"},
+	}, nil)
+
+	var out bytes.Buffer
+	reporter := NewJSONReporter(JSONReporterOpts{Output: &out})
+	reporter.now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+	runner := NewRunner(RunnerOpts{
+		HttpExecutor: mockEx,
+		Parser:       mockParser,
+		Reporter:     reporter,
+		Seed:         42,
+	})
+
+	require.Error(t, runner.Run("./some/path"))
+	require.NoError(t, reporter.Close())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4)
+
+	events := make([]map[string]any, 0, len(lines))
+	for _, line := range lines {
+		var event map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		require.Equal(t, "2023-01-01T00:00:00Z", event["time"])
+		delete(event, "time")
+		// Durations depend on the machine running the test
+		if _, ok := event["duration_ms"]; ok {
+			event["duration_ms"] = float64(0)
+		}
+		events = append(events, event)
+	}
+
+	require.Equal(t, map[string]any{
+		"event":    "sequence-start",
+		"sequence": "a.yaml",
+		"file":     "root/a.yaml",
+		"seed":     float64(42),
+	}, events[0])
+	require.Equal(t, map[string]any{
+		"event":       "call",
+		"sequence":    "a.yaml",
+		"call":        "login",
+		"type":        "http",
+		"url":         "http://some.api.com/login",
+		"method":      "POST",
+		"status":      float64(200),
+		"duration_ms": float64(0),
+		"result":      "passed",
+		"exports":     map[string]any{"token": "abc"},
+	}, events[1])
+	require.Equal(t, "fetch", events[2]["call"])
+	require.Equal(t, "GET", events[2]["method"])
+	require.Equal(t, "failed", events[2]["result"])
+	require.Contains(t, events[2]["error"], "call fetch failed 1 assert(s)")
+	require.Equal(t, []any{
+		map[string]any{
+			"jq":       ".count",
+			"op":       "gt",
+			"expected": float64(1),
+			"actual":   float64(2),
+			"passed":   true,
+		},
+		map[string]any{
+			"jq":       ".name",
+			"op":       "equal",
+			"expected": "foo",
+			"actual":   "bar",
+			"passed":   false,
+			"error":    `failed assert: .name equal: expected "foo", got "bar"`,
+		},
+	}, events[2]["asserts"])
+	require.Equal(t, "sequence-end", events[3]["event"])
+	require.Equal(t, "failed", events[3]["result"])
+}
+
+func TestReportsToStdout(t *testing.T) {
+	require.True(t, ReportsToStdout([]string{"junit=report.xml", "json"}))
+	require.False(t, ReportsToStdout([]string{"junit=report.xml", "json=report.json"}))
+	require.False(t, ReportsToStdout(nil))
+}
@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type JUnitReporterOpts struct {
+	Output io.Writer
+}
+
+func NewJUnitReporter(opts JUnitReporterOpts) *JUnitReporter {
+	return &JUnitReporter{
+		output: opts.Output,
+		suites: make(map[string]*junitTestSuite),
+	}
+}
+
+var _ Reporter = (*JUnitReporter)(nil)
+
+// JUnitReporter collects results into a JUnit XML report, written once the run is complete. Each
+// sequence becomes a testsuite, and each of its calls a testcase
+type JUnitReporter struct {
+	mu     sync.Mutex
+	output io.Writer
+	suites map[string]*junitTestSuite
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	File       string          `xml:"file,attr,omitempty"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+
+	duration time.Duration
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// sequenceCaseName is the name of the testcase used to report problems with a sequence that aren't
+// attributable to any of its calls
+const sequenceCaseName = "(sequence)"
+
+func (j *JUnitReporter) suite(report SequenceReport) *junitTestSuite {
+	suite, ok := j.suites[report.Name]
+	if !ok {
+		suite = &junitTestSuite{
+			Name:      report.Name,
+			File:      report.File,
+			Timestamp: report.Start.Format(time.RFC3339),
+		}
+		j.suites[report.Name] = suite
+	}
+	if suite.Properties == nil && report.Seed != 0 {
+		suite.Properties = []junitProperty{{Name: "seed", Value: fmt.Sprint(report.Seed)}}
+	}
+	return suite
+}
+
+func (j *JUnitReporter) SequenceStarted(report SequenceReport) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.suite(report)
+}
+
+func (j *JUnitReporter) CallFinished(report CallReport) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	suite := j.suite(SequenceReport{Name: report.Sequence})
+	testCase := junitTestCase{
+		Name:      report.Name,
+		Classname: report.Sequence,
+		Time:      junitSeconds(report.Duration),
+	}
+	switch {
+	case report.Skipped:
+		testCase.Skipped = &junitSkipped{Message: report.SkipReason}
+	case report.Err != nil:
+		failure := &junitFailure{
+			Message: report.Err.Error(),
+			Text:    strings.TrimSpace(report.Err.Error() + "\n\n" + assertDiffs(report.Err)),
+		}
+		// A call that got a response failed its expectations, otherwise it couldn't be executed at all
+		if report.Result != nil {
+			failure.Type = "failure"
+			testCase.Failure = failure
+		} else {
+			failure.Type = "error"
+			testCase.Error = failure
+		}
+		testCase.SystemOut = junitExchange(report)
+	}
+	suite.Cases = append(suite.Cases, testCase)
+}
+
+func (j *JUnitReporter) SequenceFinished(report SequenceReport) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	suite := j.suite(report)
+	suite.duration = report.Duration
+	suite.Time = junitSeconds(report.Duration)
+
+	switch {
+	case report.Skipped:
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      sequenceCaseName,
+			Classname: report.Name,
+			Time:      junitSeconds(0),
+			Skipped:   &junitSkipped{Message: report.Err.Error()},
+		})
+	case report.Err != nil:
+		for _, testCase := range suite.Cases {
+			if testCase.Failure != nil || testCase.Error != nil {
+				return
+			}
+		}
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      sequenceCaseName,
+			Classname: report.Name,
+			Time:      suite.Time,
+			Error:     &junitFailure{Message: report.Err.Error(), Type: "error", Text: report.Err.Error()},
+		})
+	}
+}
+
+// Close writes out the report
+func (j *JUnitReporter) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	report := junitTestSuites{Name: "poke"}
+	var total time.Duration
+	for _, suite := range j.suites {
+		suite.Tests = len(suite.Cases)
+		suite.Failures, suite.Errors, suite.Skipped = 0, 0, 0
+		for _, testCase := range suite.Cases {
+			switch {
+			case testCase.Failure != nil:
+				suite.Failures++
+			case testCase.Error != nil:
+				suite.Errors++
+			case testCase.Skipped != nil:
+				suite.Skipped++
+			}
+		}
+		if suite.Time == "" {
+			suite.Time = junitSeconds(0)
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		total += suite.duration
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = junitSeconds(total)
+	sort.Slice(report.Suites, func(i, k int) bool {
+		return report.Suites[i].Name < report.Suites[k].Name
+	})
+
+	out, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling junit report: %w", err)
+	}
+	if _, err := io.WriteString(j.output, xml.Header+string(out)+"\n"); err != nil {
+		return fmt.Errorf("error writing junit report: %w", err)
+	}
+	return nil
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// junitExchange renders the request and response of a call, to help diagnose failures
+func junitExchange(report CallReport) string {
+	var b strings.Builder
+	if report.Call != nil {
+		b.WriteString("request:\n")
+		if callBytes, err := yaml.Marshal(report.Call); err == nil {
+			b.Write(callBytes)
+		}
+	}
+	if report.Result != nil {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "response:\nstatus: %v\n", report.Result.StatusCode)
+		writeJUnitMetadata(&b, "headers", report.Result.Headers)
+		writeJUnitMetadata(&b, "trailers", report.Result.Trailers)
+		body := report.Result.RawBody
+		if len(body) == 0 && report.Result.Body != nil {
+			body, _ = json.MarshalIndent(report.Result.Body, "", "   ")
+		}
+		if len(body) > 0 {
+			b.WriteString("body:\n")
+			b.Write(body)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func writeJUnitMetadata(b *strings.Builder, title string, md map[string][]string) {
+	if len(md) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(b, "%v:\n", title)
+	for _, k := range keys {
+		for _, v := range md[k] {
+			fmt.Fprintf(b, "  %v: %v\n", k, v)
+		}
+	}
+}
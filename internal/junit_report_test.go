@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJUnitReporter(t *testing.T) {
+	t.Run("run", func(t *testing.T) {
+		okCall := Call{Name: "ok", Url: "http://some.api.com/ok"}
+		failCall := Call{
+			Name: "check",
+			Url:  "http://some.api.com/check",
+			Asserts: []Assert{
+				{JQ: ".name", Expected: "foo"},
+			},
+		}
+		brokenCall := Call{Name: "broken", Url: "http://some.api.com/broken"}
+
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{
+				"a.yaml": Sequence{file: "root/a.yaml", path: "root", Calls: []Call{okCall, failCall}},
+				"b.yaml": Sequence{file: "root/b.yaml", path: "root", Calls: []Call{brokenCall}},
+				"c.yaml": Sequence{file: "root/c.yaml", path: "root", DependsOn: []string{"a.yaml"}, Calls: []Call{okCall}},
+			},
+			nil,
+		)
+
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, okCall).Return(&ExecuteResult{StatusCode: 200}, nil)
+		mockEx.EXPECT().Execute(mock.Anything, failCall).Return(&ExecuteResult{
+			StatusCode: 200,
+			Headers:    map[string][]string{"Content-Type": {"application/json"}},
+			Body:       map[string]any{"name": "bar"},
+			RawBody:    []byte(`{"name":"bar"}`),
+		}, nil)
+		mockEx.EXPECT().Execute(mock.Anything, brokenCall).Return(nil, errors.New("connection refused"))
+
+		var out bytes.Buffer
+		reporter := NewJUnitReporter(JUnitReporterOpts{Output: &out})
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+			Reporter:     reporter,
+			Seed:         42,
+		})
+
+		require.Error(t, runner.Run("./some/path"))
+		require.NoError(t, reporter.Close())
+
+		var report junitTestSuites
+		require.NoError(t, xml.Unmarshal(out.Bytes(), &report))
+		require.Equal(t, 4, report.Tests)
+		require.Equal(t, 1, report.Failures)
+		require.Equal(t, 1, report.Errors)
+		require.Equal(t, 1, report.Skipped)
+		require.Len(t, report.Suites, 3)
+
+		a := report.Suites[0]
+		require.Equal(t, "a.yaml", a.Name)
+		require.Equal(t, []junitProperty{{Name: "seed", Value: "42"}}, a.Properties)
+		require.Equal(t, "root/a.yaml", a.File)
+		require.Len(t, a.Cases, 2)
+		require.Equal(t, "ok", a.Cases[0].Name)
+		require.Nil(t, a.Cases[0].Failure)
+		require.Empty(t, a.Cases[0].SystemOut)
+		require.Equal(t, "check", a.Cases[1].Name)
+		require.NotNil(t, a.Cases[1].Failure)
+		require.Contains(t, a.Cases[1].Failure.Message, `failed assert: .name equal: expected "foo", got "bar"`)
+		require.Contains(t, a.Cases[1].Failure.Text, "check: .name (-expected +actual):")
+		require.Contains(t, a.Cases[1].SystemOut, "url: http://some.api.com/check")
+		require.Contains(t, a.Cases[1].SystemOut, "status: 200")
+		require.Contains(t, a.Cases[1].SystemOut, "Content-Type: application/json")
+		require.Contains(t, a.Cases[1].SystemOut, `{"name":"bar"}`)
+
+		b := report.Suites[1]
+		require.Equal(t, "b.yaml", b.Name)
+		require.Len(t, b.Cases, 1)
+		require.NotNil(t, b.Cases[0].Error)
+		require.Contains(t, b.Cases[0].Error.Message, "connection refused")
+
+		c := report.Suites[2]
+		require.Equal(t, "c.yaml", c.Name)
+		require.Len(t, c.Cases, 1)
+		require.NotNil(t, c.Cases[0].Skipped)
+		require.Contains(t, c.Cases[0].Skipped.Message, "dependency did not succeed: a.yaml")
+	})
+
+	t.Run("skipped call", func(t *testing.T) {
+		okCall := Call{Name: "ok", Url: "http://some.api.com/ok"}
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{"a.yaml": Sequence{Calls: []Call{okCall, {Name: "never", Url: "http://some.api.com/never", RunIf: "false"}}}},
+			nil,
+		)
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, okCall).Return(&ExecuteResult{StatusCode: 200}, nil)
+
+		var out bytes.Buffer
+		reporter := NewJUnitReporter(JUnitReporterOpts{Output: &out})
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+			Reporter:     reporter,
+		})
+
+		require.NoError(t, runner.Run("./some/path"))
+		require.NoError(t, reporter.Close())
+
+		var report junitTestSuites
+		require.NoError(t, xml.Unmarshal(out.Bytes(), &report))
+		require.Equal(t, 2, report.Tests)
+		require.Equal(t, 1, report.Skipped)
+		require.Equal(t, 0, report.Failures+report.Errors)
+		require.NotNil(t, report.Suites[0].Cases[1].Skipped)
+		require.Equal(t, "run-if 'false' is not met", report.Suites[0].Cases[1].Skipped.Message)
+	})
+
+	t.Run("sequence error", func(t *testing.T) {
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{"a.yaml": Sequence{Calls: []Call{{Name: "bad", Url: "{{ .missing"}}}},
+			nil,
+		)
+
+		var out bytes.Buffer
+		reporter := NewJUnitReporter(JUnitReporterOpts{Output: &out})
+		runner := NewRunner(RunnerOpts{
+			Parser:   mockParser,
+			Reporter: reporter,
+		})
+
+		require.Error(t, runner.Run("./some/path"))
+		require.NoError(t, reporter.Close())
+
+		var report junitTestSuites
+		require.NoError(t, xml.Unmarshal(out.Bytes(), &report))
+		require.Equal(t, 1, report.Errors)
+		require.Len(t, report.Suites[0].Cases, 1)
+		require.Equal(t, "bad", report.Suites[0].Cases[0].Name)
+		require.NotNil(t, report.Suites[0].Cases[0].Error)
+	})
+}
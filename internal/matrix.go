@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrEmptyMatrix     = errors.New("matrix has no values for")
+	ErrDuplicateMatrix = errors.New("matrix has duplicate values for")
+)
+
+// expandMatrices replaces each sequence that has a matrix with a copy of it for every combination of the
+// matrix values, named after the combination
+func expandMatrices(seqs SequenceMap) (SequenceMap, error) {
+	expanded := make(SequenceMap, len(seqs))
+	for name, seq := range seqs {
+		if len(seq.Matrix) == 0 {
+			expanded[name] = seq
+			continue
+		}
+		combos, err := matrixCombinations(seq.Matrix)
+		if err != nil {
+			return nil, fmt.Errorf("error expanding matrix of %v: %w", name, err)
+		}
+		for _, combo := range combos {
+			comboSeq := seq
+			comboSeq.matrixVars = combo
+			expanded[fmt.Sprintf("%v[%v]", name, matrixLabel(combo))] = comboSeq
+		}
+	}
+	return expanded, nil
+}
+
+// matrixCombinations returns the cartesian product of the values of the matrix
+func matrixCombinations(matrix map[string][]any) ([]map[string]any, error) {
+	combos := []map[string]any{{}}
+	for _, key := range sortedKeys(matrix) {
+		values := matrix[key]
+		if err := checkMatrixValues(key, values); err != nil {
+			return nil, err
+		}
+		next := make([]map[string]any, 0, len(combos)*len(values))
+		for _, combo := range combos {
+			for _, value := range values {
+				nextCombo := make(map[string]any, len(combo)+1)
+				for k, v := range combo {
+					nextCombo[k] = v
+				}
+				nextCombo[key] = value
+				next = append(next, nextCombo)
+			}
+		}
+		combos = next
+	}
+	return combos, nil
+}
+
+// checkMatrixValues ensures a key of a matrix has values, and that each is distinct once written in the
+// name of a combination, so no two combinations share a name
+func checkMatrixValues(key string, values []any) error {
+	if len(values) == 0 {
+		return fmt.Errorf("%w %v", ErrEmptyMatrix, key)
+	}
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		label := fmt.Sprint(value)
+		if seen[label] {
+			return fmt.Errorf("%w %v: %v", ErrDuplicateMatrix, key, label)
+		}
+		seen[label] = true
+	}
+	return nil
+}
+
+func matrixLabel(combo map[string]any) string {
+	parts := make([]string, 0, len(combo))
+	for _, key := range sortedKeys(combo) {
+		parts = append(parts, fmt.Sprintf("%v=%v", key, combo[key]))
+	}
+	return strings.Join(parts, ",")
+}
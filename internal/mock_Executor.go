@@ -2,7 +2,11 @@
 
 package internal
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // MockExecutor is an autogenerated mock type for the Executor type
 type MockExecutor struct {
@@ -17,25 +21,25 @@ func (_m *MockExecutor) EXPECT() *MockExecutor_Expecter {
 	return &MockExecutor_Expecter{mock: &_m.Mock}
 }
 
-// Execute provides a mock function with given fields: call
-func (_m *MockExecutor) Execute(call Call) (*ExecuteResult, error) {
-	ret := _m.Called(call)
+// Execute provides a mock function with given fields: ctx, call
+func (_m *MockExecutor) Execute(ctx context.Context, call Call) (*ExecuteResult, error) {
+	ret := _m.Called(ctx, call)
 
 	var r0 *ExecuteResult
 	var r1 error
-	if rf, ok := ret.Get(0).(func(Call) (*ExecuteResult, error)); ok {
-		return rf(call)
+	if rf, ok := ret.Get(0).(func(context.Context, Call) (*ExecuteResult, error)); ok {
+		return rf(ctx, call)
 	}
-	if rf, ok := ret.Get(0).(func(Call) *ExecuteResult); ok {
-		r0 = rf(call)
+	if rf, ok := ret.Get(0).(func(context.Context, Call) *ExecuteResult); ok {
+		r0 = rf(ctx, call)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*ExecuteResult)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(Call) error); ok {
-		r1 = rf(call)
+	if rf, ok := ret.Get(1).(func(context.Context, Call) error); ok {
+		r1 = rf(ctx, call)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -49,14 +53,15 @@ type MockExecutor_Execute_Call struct {
 }
 
 // Execute is a helper method to define mock.On call
+//   - ctx context.Context
 //   - call Call
-func (_e *MockExecutor_Expecter) Execute(call interface{}) *MockExecutor_Execute_Call {
-	return &MockExecutor_Execute_Call{Call: _e.mock.On("Execute", call)}
+func (_e *MockExecutor_Expecter) Execute(ctx interface{}, call interface{}) *MockExecutor_Execute_Call {
+	return &MockExecutor_Execute_Call{Call: _e.mock.On("Execute", ctx, call)}
 }
 
-func (_c *MockExecutor_Execute_Call) Run(run func(call Call)) *MockExecutor_Execute_Call {
+func (_c *MockExecutor_Execute_Call) Run(run func(ctx context.Context, call Call)) *MockExecutor_Execute_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(Call))
+		run(args[0].(context.Context), args[1].(Call))
 	})
 	return _c
 }
@@ -66,7 +71,7 @@ func (_c *MockExecutor_Execute_Call) Return(_a0 *ExecuteResult, _a1 error) *Mock
 	return _c
 }
 
-func (_c *MockExecutor_Execute_Call) RunAndReturn(run func(Call) (*ExecuteResult, error)) *MockExecutor_Execute_Call {
+func (_c *MockExecutor_Execute_Call) RunAndReturn(run func(context.Context, Call) (*ExecuteResult, error)) *MockExecutor_Execute_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -22,25 +22,25 @@ func (_m *MockIHttpClient) EXPECT() *MockIHttpClient_Expecter {
 	return &MockIHttpClient_Expecter{mock: &_m.Mock}
 }
 
-// Do provides a mock function with given fields: req
-func (_m *MockIHttpClient) Do(req *http.Request) (*http.Response, error) {
-	ret := _m.Called(req)
+// Do provides a mock function with given fields: req, opts
+func (_m *MockIHttpClient) Do(req *http.Request, opts TransportOptions) (*http.Response, error) {
+	ret := _m.Called(req, opts)
 
 	var r0 *http.Response
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*http.Request) (*http.Response, error)); ok {
-		return rf(req)
+	if rf, ok := ret.Get(0).(func(*http.Request, TransportOptions) (*http.Response, error)); ok {
+		return rf(req, opts)
 	}
-	if rf, ok := ret.Get(0).(func(*http.Request) *http.Response); ok {
-		r0 = rf(req)
+	if rf, ok := ret.Get(0).(func(*http.Request, TransportOptions) *http.Response); ok {
+		r0 = rf(req, opts)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*http.Response)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*http.Request) error); ok {
-		r1 = rf(req)
+	if rf, ok := ret.Get(1).(func(*http.Request, TransportOptions) error); ok {
+		r1 = rf(req, opts)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -55,13 +55,14 @@ type MockIHttpClient_Do_Call struct {
 
 // Do is a helper method to define mock.On call
 //   - req *http.Request
-func (_e *MockIHttpClient_Expecter) Do(req interface{}) *MockIHttpClient_Do_Call {
-	return &MockIHttpClient_Do_Call{Call: _e.mock.On("Do", req)}
+//   - opts TransportOptions
+func (_e *MockIHttpClient_Expecter) Do(req interface{}, opts interface{}) *MockIHttpClient_Do_Call {
+	return &MockIHttpClient_Do_Call{Call: _e.mock.On("Do", req, opts)}
 }
 
-func (_c *MockIHttpClient_Do_Call) Run(run func(req *http.Request)) *MockIHttpClient_Do_Call {
+func (_c *MockIHttpClient_Do_Call) Run(run func(req *http.Request, opts TransportOptions)) *MockIHttpClient_Do_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(*http.Request))
+		run(args[0].(*http.Request), args[1].(TransportOptions))
 	})
 	return _c
 }
@@ -71,39 +72,7 @@ func (_c *MockIHttpClient_Do_Call) Return(_a0 *http.Response, _a1 error) *MockIH
 	return _c
 }
 
-func (_c *MockIHttpClient_Do_Call) RunAndReturn(run func(*http.Request) (*http.Response, error)) *MockIHttpClient_Do_Call {
-	_c.Call.Return(run)
-	return _c
-}
-
-// SetNoTLSVerify provides a mock function with given fields:
-func (_m *MockIHttpClient) SetNoTLSVerify() {
-	_m.Called()
-}
-
-// MockIHttpClient_SetNoTLSVerify_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetNoTLSVerify'
-type MockIHttpClient_SetNoTLSVerify_Call struct {
-	*mock.Call
-}
-
-// SetNoTLSVerify is a helper method to define mock.On call
-func (_e *MockIHttpClient_Expecter) SetNoTLSVerify() *MockIHttpClient_SetNoTLSVerify_Call {
-	return &MockIHttpClient_SetNoTLSVerify_Call{Call: _e.mock.On("SetNoTLSVerify")}
-}
-
-func (_c *MockIHttpClient_SetNoTLSVerify_Call) Run(run func()) *MockIHttpClient_SetNoTLSVerify_Call {
-	_c.Call.Run(func(args mock.Arguments) {
-		run()
-	})
-	return _c
-}
-
-func (_c *MockIHttpClient_SetNoTLSVerify_Call) Return() *MockIHttpClient_SetNoTLSVerify_Call {
-	_c.Call.Return()
-	return _c
-}
-
-func (_c *MockIHttpClient_SetNoTLSVerify_Call) RunAndReturn(run func()) *MockIHttpClient_SetNoTLSVerify_Call {
+func (_c *MockIHttpClient_Do_Call) RunAndReturn(run func(*http.Request, TransportOptions) (*http.Response, error)) *MockIHttpClient_Do_Call {
 	_c.Call.Return(run)
 	return _c
 }
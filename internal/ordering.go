@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnknownDependency = errors.New("unknown dependency")
+	ErrDependencyCycle   = errors.New("dependency cycle")
+	ErrDependencyNotMet  = errors.New("dependency did not succeed")
+)
+
+// orderSequences returns the names of the given sequences in the order they should be executed, along
+// with the resolved names of each sequences dependencies. Sequences are sorted by name, and then
+// reordered only as much as needed to place every sequence after the sequences it depends on
+func orderSequences(seqs SequenceMap) ([]string, map[string][]string, error) {
+	names := make([]string, 0, len(seqs))
+	for name := range seqs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Sequences with a matrix have a copy for each combination, all sharing the same file
+	byFile := make(map[string][]string, len(seqs))
+	for _, name := range names {
+		file := filepath.Clean(seqs[name].file)
+		byFile[file] = append(byFile[file], name)
+	}
+
+	deps := make(map[string][]string, len(seqs))
+	for _, name := range names {
+		seq := seqs[name]
+		for _, dep := range seq.DependsOn {
+			path := dep
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(seq.path, path)
+			}
+			depNames, ok := byFile[filepath.Clean(path)]
+			if !ok {
+				return nil, nil, fmt.Errorf("%w: %v depends on %v, which is not part of this run", ErrUnknownDependency, name, dep)
+			}
+			deps[name] = append(deps[name], depNames...)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(seqs))
+	order := make([]string, 0, len(seqs))
+
+	var visit func(name string, chain []string) error
+	visit = func(name string, chain []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %v", ErrDependencyCycle, strings.Join(append(chain, name), " -> "))
+		}
+
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep, append(chain, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return order, deps, nil
+}
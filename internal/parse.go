@@ -7,6 +7,8 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/rs/zerolog"
@@ -15,6 +17,8 @@ import (
 
 var ErrWalkError = errors.New("error walking directory")
 
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
 type SequenceMap map[string]Sequence
 
 type Parser interface {
@@ -41,7 +45,24 @@ type FSParser struct {
 func (f *FSParser) ParseSequences(root string) (SequenceMap, error) {
 	sequences := SequenceMap{}
 
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+	err := walkSequenceFiles(root, func(path string, relPath string) error {
+		seq, err := f.ParseSingleSequence(path)
+		if err != nil {
+			return fmt.Errorf("error parsing sequence: %w", err)
+		}
+		sequences[relPath] = seq
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrWalkError, err)
+	}
+	return sequences, nil
+}
+
+// walkSequenceFiles calls fn with the path of every sequence file under root, along with its path
+// relative to root
+func walkSequenceFiles(root string, fn func(path string, relPath string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -50,24 +71,20 @@ func (f *FSParser) ParseSequences(root string) (SequenceMap, error) {
 			return nil
 		}
 
+		if isVariableFile(root, path) {
+			return nil
+		}
+
 		if strings.HasSuffix(d.Name(), ".yaml") || strings.HasSuffix(d.Name(), ".yml") {
-			seq, err := f.ParseSingleSequence(path)
-			if err != nil {
-				return fmt.Errorf("error parsing sequence: %w", err)
-			}
 			relPath, err := filepath.Rel(root, path)
 			if err != nil {
 				return fmt.Errorf("error computing relative path: %w", err)
 			}
-			sequences[relPath] = seq
+			return fn(path, relPath)
 		}
 
 		return nil
 	})
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrWalkError, err)
-	}
-	return sequences, nil
 }
 
 func (f *FSParser) ParseSingleSequence(path string) (Sequence, error) {
@@ -80,11 +97,19 @@ func (f *FSParser) ParseSingleSequence(path string) (Sequence, error) {
 	var seq Sequence
 	err = decoder.Decode(&seq)
 	if err != nil {
-		return Sequence{}, fmt.Errorf("error unmarshalling: %w", err)
+		return Sequence{}, fmt.Errorf("error unmarshalling: %w", yamlErrorsAt(path, err))
 	}
 
-	seq.importedCalls = make(map[string]map[string]Call)
+	seq.imported = make(map[string]*Sequence)
+	seq.importPositions = make(map[string]Position)
+
+	// Decoding succeeded, so the content is known to be valid YAML
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err == nil {
+		setPositions(&seq, &root, path)
+	}
 
+	seq.file = path
 	seq.path = filepath.Dir(path)
 
 	return seq, nil
@@ -111,3 +136,93 @@ func (f *FSParser) Parse(path string) (SequenceMap, error) {
 	}
 	return sequences, nil
 }
+
+// setPositions records where each import, call, export and assert of the sequence was defined
+func setPositions(seq *Sequence, root *yaml.Node, file string) {
+	pos := func(node *yaml.Node) Position {
+		return Position{File: file, Line: node.Line, Column: node.Column}
+	}
+	setAssertPositions := func(asserts []Assert, node *yaml.Node) {
+		for i, assNode := range sequenceItems(node) {
+			if i < len(asserts) {
+				asserts[i].pos = pos(assNode)
+			}
+		}
+	}
+
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	if imports := mappingValue(root, "imports"); imports != nil && imports.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(imports.Content); i += 2 {
+			seq.importPositions[imports.Content[i].Value] = pos(imports.Content[i])
+		}
+	}
+
+	setCallPositions := func(calls []Call, node *yaml.Node) {
+		for i, callNode := range sequenceItems(node) {
+			if i >= len(calls) {
+				break
+			}
+			call := &calls[i]
+			call.pos = pos(callNode)
+			for j, expNode := range sequenceItems(mappingValue(callNode, "exports")) {
+				if j < len(call.Exports) {
+					call.Exports[j].pos = pos(expNode)
+				}
+			}
+			setAssertPositions(call.Asserts, mappingValue(callNode, "asserts"))
+			if call.Retry != nil && call.Retry.Until != nil {
+				until := mappingValue(mappingValue(callNode, "retry"), "until")
+				setAssertPositions(call.Retry.Until.Asserts, mappingValue(until, "asserts"))
+			}
+		}
+	}
+	setCallPositions(seq.Setup, mappingValue(root, "setup"))
+	setCallPositions(seq.Calls, mappingValue(root, "calls"))
+	setCallPositions(seq.Teardown, mappingValue(root, "teardown"))
+}
+
+// mappingValue returns the value of key within a mapping node, or nil if there isn't one
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func sequenceItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+// yamlErrorsAt attributes each of the problems reported by the YAML decoder to their line in file
+func yamlErrorsAt(file string, err error) error {
+	var msgs []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	} else {
+		msgs = []string{err.Error()}
+	}
+
+	errs := make([]error, 0, len(msgs))
+	for _, msg := range msgs {
+		match := yamlErrorLine.FindStringSubmatch(msg)
+		if match == nil {
+			errs = append(errs, &PositionError{Pos: Position{File: file}, Err: errors.New(msg)})
+			continue
+		}
+		line, _ := strconv.Atoi(match[1])
+		errs = append(errs, &PositionError{Pos: Position{File: file, Line: line}, Err: errors.New(match[2])})
+	}
+	return errors.Join(errs...)
+}
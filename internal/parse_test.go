@@ -17,14 +17,24 @@ func TestFSParser(t *testing.T) {
 			t,
 			SequenceMap{
 				"foo_seq.yaml": {
-					Calls: []Call{{Url: "https://foo.bar.com/foo_seq_top"}},
+					Calls: []Call{{
+						Url: "https://foo.bar.com/foo_seq_top",
+						pos: Position{File: "testdata/parser/happy/foo_seq.yaml", Line: 2, Column: 3},
+					}},
+					file: "testdata/parser/happy/foo_seq.yaml",
 					path: "testdata/parser/happy",
-					importedCalls: map[string]map[string]Call{},
+					imported: map[string]*Sequence{},
+					importPositions: map[string]Position{},
 				},
 				"subdir/foo_seq.yml": {
-					Calls: []Call{{Url: "https://foo.bar.com/foo_seq_inner"}},
+					Calls: []Call{{
+						Url: "https://foo.bar.com/foo_seq_inner",
+						pos: Position{File: "testdata/parser/happy/subdir/foo_seq.yml", Line: 2, Column: 3},
+					}},
+					file: "testdata/parser/happy/subdir/foo_seq.yml",
 					path: "testdata/parser/happy/subdir",
-					importedCalls: map[string]map[string]Call{},
+					imported: map[string]*Sequence{},
+					importPositions: map[string]Position{},
 				},
 			},
 			got,
@@ -36,6 +46,23 @@ func TestFSParser(t *testing.T) {
 
 		_, err := parser.Parse("./testdata/parser/unknown_key.yaml")
 		require.Error(t, err)
-		require.ErrorContains(t, err, "field assertions not found")
+		require.ErrorContains(t, err, "testdata/parser/unknown_key.yaml:3: field assertions not found")
+	})
+
+	t.Run("positions", func(t *testing.T) {
+		parser := NewFSParser(FSParserOpts{})
+
+		seq, err := parser.ParseSingleSequence("testdata/parser/positions.yaml")
+		require.NoError(t, err)
+
+		at := func(line int, col int) Position {
+			return Position{File: "testdata/parser/positions.yaml", Line: line, Column: col}
+		}
+		require.Equal(t, map[string]Position{"lib": at(2, 3)}, seq.importPositions)
+		require.Equal(t, at(4, 3), seq.Calls[0].pos)
+		require.Equal(t, at(7, 5), seq.Calls[0].Exports[0].pos)
+		require.Equal(t, at(10, 5), seq.Calls[0].Asserts[0].pos)
+		require.Equal(t, at(12, 3), seq.Calls[1].pos)
+		require.Equal(t, at(17, 9), seq.Calls[1].Retry.Until.Asserts[0].pos)
 	})
 }
@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Reporter receives the results of a run as it progresses. Sequences may execute concurrently, so
+// implementations must be safe for concurrent use
+type Reporter interface {
+	SequenceStarted(report SequenceReport)
+	CallFinished(report CallReport)
+	SequenceFinished(report SequenceReport)
+	// Close is called once the run is complete
+	Close() error
+}
+
+type SequenceReport struct {
+	Name     string
+	File     string
+	Start    time.Time
+	Duration time.Duration
+	// Skipped is set if the sequence was never executed because one of its dependencies did not succeed
+	Skipped bool
+	Err     error
+	// TeardownErr is the failure of the sequence's teardown, if any, which is separate from the outcome of
+	// its calls
+	TeardownErr error
+	// Seed is the seed of the run, which the random values generated by the sequence's templates are
+	// derived from
+	Seed int64
+}
+
+type CallReport struct {
+	Sequence string
+	Name     string
+	// Skipped is set if the call wasn't executed because of its run-if or skip-if, with SkipReason
+	// explaining why. Err is nil for skipped calls
+	Skipped    bool
+	SkipReason string
+	// Call is the call as executed, after templating. It's nil if the call failed before it could be
+	// executed
+	Call *Call
+	// Result is the response to the final attempt at the call, nil if no response was received
+	Result *ExecuteResult
+	// Asserts holds the outcome of each of the call's asserts, if they were evaluated
+	Asserts []AssertResult
+	// Exports holds the values exported by the call, keyed by the name they were exported as
+	Exports  map[string]any
+	Duration time.Duration
+	Err      error
+}
+
+// NewReporter builds a reporter from a list of specs of the form `kind=path`, such as
+// `junit=report.xml`. A json report without a path is written to stdout
+//
+//nolint:ireturn
+func NewReporter(specs []string) (Reporter, error) {
+	reporters := make(multiReporter, 0, len(specs))
+	for _, spec := range specs {
+		rep, err := newReporterFromSpec(spec)
+		if err != nil {
+			// Don't leave behind any files already opened
+			_ = reporters.Close()
+			return nil, fmt.Errorf("error creating report '%v': %w", spec, err)
+		}
+		reporters = append(reporters, rep)
+	}
+	return reporters, nil
+}
+
+// ReportsToStdout returns whether any of the specs would write a report to stdout, in which case nothing
+// else should be written there
+func ReportsToStdout(specs []string) bool {
+	for _, spec := range specs {
+		kind, path, _ := strings.Cut(spec, "=")
+		if kind == "json" && path == "" {
+			return true
+		}
+	}
+	return false
+}
+
+//nolint:ireturn
+func newReporterFromSpec(spec string) (Reporter, error) {
+	kind, path, _ := strings.Cut(spec, "=")
+	switch kind {
+	case "junit":
+		if path == "" {
+			return nil, fmt.Errorf("junit report requires a path, e.g. junit=report.xml")
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("error creating report file: %w", err)
+		}
+		return &fileReporter{
+			Reporter: NewJUnitReporter(JUnitReporterOpts{Output: file}),
+			file:     file,
+		}, nil
+	case "json":
+		if path == "" {
+			return NewJSONReporter(JSONReporterOpts{Output: os.Stdout}), nil
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("error creating report file: %w", err)
+		}
+		return &fileReporter{
+			Reporter: NewJSONReporter(JSONReporterOpts{Output: file}),
+			file:     file,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown report kind '%v', try one of [junit, json]", kind)
+	}
+}
+
+// fileReporter closes the file a reporter is writing to once the reporter is closed
+type fileReporter struct {
+	Reporter
+	file *os.File
+}
+
+func (f *fileReporter) Close() error {
+	return errors.Join(f.Reporter.Close(), f.file.Close())
+}
+
+type multiReporter []Reporter
+
+func (m multiReporter) SequenceStarted(report SequenceReport) {
+	for _, rep := range m {
+		rep.SequenceStarted(report)
+	}
+}
+
+func (m multiReporter) CallFinished(report CallReport) {
+	for _, rep := range m {
+		rep.CallFinished(report)
+	}
+}
+
+func (m multiReporter) SequenceFinished(report SequenceReport) {
+	for _, rep := range m {
+		rep.SequenceFinished(report)
+	}
+}
+
+func (m multiReporter) Close() error {
+	var errs []error
+	for _, rep := range m {
+		errs = append(errs, rep.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -2,23 +2,45 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
-
+	"strings"
+	"sync"
 	"text/template"
+	"time"
 
-	"github.com/google/go-cmp/cmp"
+	"github.com/Masterminds/sprig/v3"
 	"github.com/itchyny/gojq"
 	"github.com/rs/zerolog"
 	"gopkg.in/yaml.v3"
 )
 
+var (
+	ErrInterrupted    = errors.New("run interrupted")
+	ErrTeardownFailed = errors.New("teardown failed")
+	ErrHooksCalls     = errors.New("hooks file must only contain setup and teardown, not calls")
+	ErrTypedValue     = errors.New("typed must be the entire value of a field")
+	ErrTextValue      = errors.New("objects and lists can't be written as text")
+)
+
+// typedPlaceholder stands in for the values passed to typed in the output of a template
+const typedPlaceholder = "\x00typed:%v\x00"
+
+// The names the setup and teardown of the global hooks file are reported as
+const (
+	globalSetupName    = "(setup)"
+	globalTeardownName = "(teardown)"
+)
+
 type RunnerOpts struct {
 	Logger       zerolog.Logger
 	HttpExecutor Executor
@@ -26,17 +48,58 @@ type RunnerOpts struct {
 	Parser       Parser
 	Output       io.Writer
 	FailFast     bool
+	// Concurrency is the number of sequences to execute at once, values less than 1 are treated as 1
+	Concurrency int
+	// LogOutput is the writer backing Logger. When running concurrently, log events for a sequence are
+	// held and written here as a group once the sequence finishes. If unset, logs are not grouped
+	LogOutput io.Writer
+	// Reporter receives the results of each sequence and call as they finish. If unset, results are only
+	// logged
+	Reporter Reporter
+	// Variables are given to every sequence, in addition to its own vars
+	Variables Variables
+	// Hooks is the path of a sequence file whose setup is executed before any other sequence, and whose
+	// teardown is executed after all of them. Optional
+	Hooks string
+	// Seed makes the random values generated by templates reproducible. If 0, a random seed is used
+	Seed int64
+	// TeardownTimeout bounds how long the teardown of each sequence, or of the hooks file, may take.
+	// Defaults to 5 minutes
+	TeardownTimeout time.Duration
 }
 
 func NewRunner(opts RunnerOpts) *Runner {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	reporter := opts.Reporter
+	if reporter == nil {
+		reporter = multiReporter{}
+	}
+	teardownTimeout := opts.TeardownTimeout
+	if teardownTimeout <= 0 {
+		teardownTimeout = 5 * time.Minute
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
 	return &Runner{
-		log:          opts.Logger,
-		httpExecutor: opts.HttpExecutor,
-		grpcExecutor: opts.GrpcExecutor,
-		parser:       opts.Parser,
-		ctxVariables: make(map[string]any),
-		output:       opts.Output,
-		failFast:     opts.FailFast,
+		log:             opts.Logger,
+		httpExecutor:    opts.HttpExecutor,
+		grpcExecutor:    opts.GrpcExecutor,
+		parser:          opts.Parser,
+		output:          opts.Output,
+		failFast:        opts.FailFast,
+		concurrency:     concurrency,
+		logOutput:       opts.LogOutput,
+		reporter:        reporter,
+		variables:       opts.Variables,
+		hooks:           opts.Hooks,
+		seed:            seed,
+		sleep:           sleepContext,
+		teardownTimeout: teardownTimeout,
 	}
 }
 
@@ -45,125 +108,757 @@ type Runner struct {
 	httpExecutor Executor
 	grpcExecutor Executor
 	parser       Parser
-	ctxVariables map[string]any
 	output       io.Writer
 	failFast     bool
+	concurrency  int
+	logOutput    io.Writer
+	reporter     Reporter
+	variables    Variables
+	hooks        string
+	seed         int64
+	flushMu      sync.Mutex
+	sleep        func(context.Context, time.Duration) error
+	// teardownTimeout bounds how long the teardown of each sequence may take, once it's started
+	teardownTimeout time.Duration
+	// globals holds the values exported by the setup of the hooks file, given to every sequence
+	globals map[string]any
+}
+
+// sequenceRun holds the state scoped to a single execution of a sequence, so that concurrently running
+// sequences never share variables or interleave their output
+type sequenceRun struct {
+	*Runner
+	ctx          context.Context
+	name         string
+	seq          *Sequence
+	log          zerolog.Logger
+	output       io.Writer
+	ctxVariables map[string]any
+	// exports holds the values exported by calls so far, which are also in ctxVariables
+	exports map[string]any
+	group   *groupBuffer
+	// rand is the source of random values for templates
+	rand *rand.Rand
 }
 
 func (r *Runner) Run(path string) error {
+	return r.RunContext(context.Background(), path)
+}
+
+// RunContext executes the sequences at path. Once ctx is done no further calls are executed, but the
+// teardown of every sequence that was started is still executed
+func (r *Runner) RunContext(ctx context.Context, path string) error {
+	// Logged so that a run using random values can be reproduced with --seed
+	r.log.Info().Int64("seed", r.seed).Msg("starting run")
+
 	sequences, err := r.parser.Parse(path)
 	if err != nil {
 		return fmt.Errorf("error parsing: %w", err)
 	}
 
-	return r.runSequences(sequences)
+	if r.hooks == "" {
+		return r.runSequences(ctx, sequences)
+	}
+	return r.runWithHooks(ctx, sequences)
+}
+
+// runWithHooks executes the sequences between the setup and teardown of the hooks file. The teardown is
+// executed even if the setup or any sequence fails
+func (r *Runner) runWithHooks(ctx context.Context, sequences SequenceMap) error {
+	hooks, err := r.parser.ParseSingleSequence(r.hooks)
+	if err != nil {
+		return fmt.Errorf("error parsing hooks: %w", err)
+	}
+	if len(hooks.Calls) > 0 {
+		return fmt.Errorf("%w: %v", ErrHooksCalls, r.hooks)
+	}
+	// The hooks file may live alongside the sequences, but isn't one itself. It may be given by a different
+	// path than the sequences were found by, so compare the files themselves
+	hooksInfo, err := os.Stat(r.hooks)
+	if err != nil {
+		return fmt.Errorf("error reading hooks: %w", err)
+	}
+	for name, seq := range sequences {
+		if seqInfo, err := os.Stat(seq.file); err == nil && os.SameFile(seqInfo, hooksInfo) {
+			delete(sequences, name)
+		}
+	}
+
+	setup := r.newSequenceRun(ctx, globalSetupName)
+	if err := setup.prepare(&hooks); err != nil {
+		return fmt.Errorf("error preparing hooks: %w", err)
+	}
+	if len(hooks.Setup) > 0 {
+		err = setup.runAsSequence(func() error {
+			return setup.runCalls(&hooks, hooks.Setup, "", nil)
+		})
+	}
+	if err != nil {
+		err = fmt.Errorf("error during global setup: %w", err)
+	} else {
+		r.globals = setup.exports
+		err = r.runSequences(ctx, sequences)
+	}
+
+	if len(hooks.Teardown) == 0 {
+		return err
+	}
+	teardown := r.newSequenceRun(ctx, globalTeardownName)
+	teardown.seq = setup.seq
+	teardown.ctxVariables = setup.ctxVariables
+	teardownErr := teardown.runAsSequence(func() error {
+		return teardown.runTeardown(&hooks, "")
+	})
+	if teardownErr != nil {
+		teardownErr = fmt.Errorf("%w: global teardown: %w", ErrTeardownFailed, teardownErr)
+	}
+	return errors.Join(err, teardownErr)
+}
+
+// runAsSequence executes fn, reporting it as a sequence of its own
+func (s *sequenceRun) runAsSequence(fn func() error) error {
+	defer s.flush()
+
+	report := SequenceReport{
+		Name:  s.name,
+		File:  s.seq.file,
+		Start: time.Now(),
+		Seed:  s.seed,
+	}
+	s.reporter.SequenceStarted(report)
+	s.log.Info().Msg("executing hooks")
+	err := fn()
+	report.Duration = time.Since(report.Start)
+	report.Err = err
+	s.reporter.SequenceFinished(report)
+	if err != nil {
+		s.log.Err(err).Msg("encountered error during execution")
+	}
+	return err
 }
 
-func (r *Runner) runSequences(seqs map[string]Sequence) error {
+type sequenceStatus int
+
+const (
+	sequencePending sequenceStatus = iota
+	sequenceRunning
+	sequenceSucceeded
+	sequenceFailed
+	sequenceSkipped
+)
+
+type sequenceResult struct {
+	name string
+	err  error
+}
+
+func (r *Runner) runSequences(ctx context.Context, seqs SequenceMap) error {
+	seqs, err := expandMatrices(seqs)
+	if err != nil {
+		return err
+	}
+
+	order, deps, err := orderSequences(seqs)
+	if err != nil {
+		return fmt.Errorf("error ordering sequences: %w", err)
+	}
+
 	var errs []error
+	statuses := make(map[string]sequenceStatus, len(order))
+	results := make(chan sequenceResult)
+	running := 0
+	failed := false
 
-	for name, seq := range seqs {
-		r.log.Info().Str("sequence", name).Msg("executing sequence")
-		if err := r.runSingleSequence(seq); err != nil {
-			r.log.Err(err).Msg("encountered error during execution")
-			errs = append(errs, fmt.Errorf("error during sequence %v: %w", name, err))
-			if r.failFast {
+	for {
+		// Walk in execution order, starting anything whose dependencies are met until all workers are
+		// busy. Since dependencies always come first, a failure propagates to all dependents in one pass
+		for _, name := range order {
+			if statuses[name] != sequencePending || running >= r.concurrency {
+				continue
+			}
+			if r.failFast && failed {
 				break
 			}
+			if ctx.Err() != nil {
+				statuses[name] = sequenceSkipped
+				skipErr := fmt.Errorf("sequence %v skipped: %w", name, ErrInterrupted)
+				r.reporter.SequenceFinished(SequenceReport{
+					Name:    name,
+					File:    seqs[name].file,
+					Start:   time.Now(),
+					Skipped: true,
+					Err:     skipErr,
+					Seed:    r.seed,
+				})
+				errs = append(errs, skipErr)
+				continue
+			}
+
+			ready := true
+			for _, dep := range deps[name] {
+				switch statuses[dep] {
+				case sequenceSucceeded:
+				case sequenceFailed, sequenceSkipped:
+					ready = false
+					statuses[name] = sequenceSkipped
+					r.log.Warn().Str("sequence", name).Str("dependency", dep).Msg("skipping sequence, dependency did not succeed")
+					skipErr := fmt.Errorf("sequence %v skipped: %w: %v", name, ErrDependencyNotMet, dep)
+					r.reporter.SequenceFinished(SequenceReport{
+						Name:    name,
+						File:    seqs[name].file,
+						Start:   time.Now(),
+						Skipped: true,
+						Err:     skipErr,
+						Seed:    r.seed,
+					})
+					errs = append(errs, skipErr)
+				default:
+					ready = false
+				}
+				if !ready {
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			statuses[name] = sequenceRunning
+			running++
+			go func(name string) {
+				results <- sequenceResult{name: name, err: r.runNamedSequence(ctx, name, seqs[name])}
+			}(name)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		res := <-results
+		running--
+		if res.err != nil {
+			statuses[res.name] = sequenceFailed
+			failed = true
+			errs = append(errs, res.err)
+		} else {
+			statuses[res.name] = sequenceSucceeded
 		}
-		// Reset variables at the end of a sequence so we don't bleed over
-		r.ctxVariables = map[string]any{}
 	}
 
 	return errors.Join(errs...)
 }
 
-func (r *Runner) runSingleSequence(seq Sequence) error {
-	// Set any predefined global vars
-	if seq.Vars != nil {
-		for k, v := range seq.Vars {
-			r.ctxVariables[k] = v
+func (r *Runner) newSequenceRun(ctx context.Context, name string) *sequenceRun {
+	run := &sequenceRun{
+		Runner:       r,
+		ctx:          ctx,
+		name:         name,
+		log:          r.log.With().Str("sequence", name).Logger(),
+		output:       r.output,
+		ctxVariables: make(map[string]any),
+		exports:      make(map[string]any),
+		rand:         sequenceRand(r.seed, name),
+	}
+
+	// Only bother grouping if there's something to interleave with
+	if r.concurrency > 1 {
+		run.group = &groupBuffer{}
+		if r.logOutput != nil {
+			run.log = run.log.Output(run.group.writerFor(r.logOutput))
+		}
+		if r.output != nil {
+			run.output = run.group.writerFor(r.output)
 		}
 	}
-	if err := r.resolveImports(&seq); err != nil {
+
+	return run
+}
+
+func (r *Runner) runNamedSequence(ctx context.Context, name string, seq Sequence) error {
+	run := r.newSequenceRun(ctx, name)
+	defer run.flush()
+
+	report := SequenceReport{
+		Name:  name,
+		File:  seq.file,
+		Start: time.Now(),
+		Seed:  r.seed,
+	}
+	r.reporter.SequenceStarted(report)
+
+	run.log.Info().Msg("executing sequence")
+	err, teardownErr := run.runSingleSequence(seq)
+
+	report.Duration = time.Since(report.Start)
+	report.Err = err
+	report.TeardownErr = teardownErr
+	r.reporter.SequenceFinished(report)
+
+	if err != nil {
+		run.log.Err(err).Msg("encountered error during execution")
+		err = fmt.Errorf("error during sequence %v: %w", name, err)
+	}
+	if teardownErr != nil {
+		run.log.Err(teardownErr).Msg("encountered error during teardown")
+		teardownErr = fmt.Errorf("%w: sequence %v: %w", ErrTeardownFailed, name, teardownErr)
+	}
+	return errors.Join(err, teardownErr)
+}
+
+// flush writes out any held output for the sequence in one block
+func (s *sequenceRun) flush() {
+	if s.group == nil {
+		return
+	}
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	if err := s.group.flush(); err != nil {
+		s.Runner.log.Err(err).Msg("error writing sequence output")
+	}
+}
+
+// runSingleSequence executes the setup, calls and teardown of the sequence. The teardown is executed
+// even if the setup or calls fail, and any problems with it are returned separately
+func (s *sequenceRun) runSingleSequence(seq Sequence) (error, error) {
+	if err := s.prepare(&seq); err != nil {
+		return err, nil
+	}
+
+	var err error
+	if err = s.runCalls(&seq, seq.Setup, "setup/", nil); err != nil {
+		err = fmt.Errorf("error during setup: %w", err)
+	} else {
+		err = s.runCalls(&seq, seq.Calls, "", nil)
+	}
+
+	return err, s.runTeardown(&seq, "teardown/")
+}
+
+// prepare resolves the imports of the sequence, and sets the variables it starts with
+func (s *sequenceRun) prepare(seq *Sequence) error {
+	if err := s.resolveImports(seq, nil); err != nil {
 		return fmt.Errorf("error resolving imports: %w", err)
 	}
+	s.seq = seq
 
-	for idx, c := range seq.Calls {
-		call := c
-		if c.FromImport != nil {
-			impSeq, ok := seq.importedCalls[c.FromImport.Name]
-			if !ok {
-				return fmt.Errorf("unable to find import %v", c.FromImport.Name)
-			}
-			impCall, ok := impSeq[c.FromImport.Call]
-			if !ok {
-				return fmt.Errorf("unable to find call %v in imported sequence %v", c.FromImport.Call, c.FromImport.Name)
-			}
-			call = impCall
+	// Set any predefined global vars. Imported vars are defaults for those of the sequence, which override
+	// those of the environment, but not any given explicitly for the run, or exported by the global setup.
+	// The matrix takes precedence over everything, so a combination always runs with the values it's
+	// named after
+	layers := []map[string]any{s.variables.Env}
+	layers = append(layers, importedVars(seq)...)
+	layers = append(layers, seq.Vars, s.variables.Overrides, s.globals, seq.matrixVars)
+	for _, vars := range layers {
+		for k, v := range vars {
+			s.ctxVariables[k] = v
 		}
-		name := call.Name
-		if name == "" {
-			name = fmt.Sprintf("call_%v", idx)
+	}
+	return nil
+}
+
+// runCalls executes each of the calls of seq in turn, stopping at the first failure. seq may be the
+// sequence being run or one it imports. The names of the calls are prefixed with prefix, and params are
+// given to each of them
+func (s *sequenceRun) runCalls(seq *Sequence, calls []Call, prefix string, params map[string]any) error {
+	for idx, c := range calls {
+		if s.ctx.Err() != nil {
+			return ErrInterrupted
 		}
-		r.log.Info().Str("call", name).Msg("executing call")
-		call, err := r.evaluateTemplate(call, seq.path)
-		if err != nil {
+		if err := s.runStep(seq, idx, c, prefix, params); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		exec, err := r.getClient(call.Type)
-		if err != nil {
-			return fmt.Errorf("error creating request client: %w", err)
+// runTeardown executes every teardown call of seq, regardless of whether earlier ones fail or the run
+// has been interrupted
+func (s *sequenceRun) runTeardown(seq *Sequence, prefix string) error {
+	if len(seq.Teardown) == 0 {
+		return nil
+	}
+	// Teardown is cleaning up after the run, so must complete even once it's interrupted, but mustn't be
+	// able to hang forever
+	ctx, cancel := context.WithTimeout(context.Background(), s.teardownTimeout)
+	defer cancel()
+	s.ctx = ctx
+	s.log.Info().Msg("executing teardown")
+
+	var errs []error
+	for idx, c := range seq.Teardown {
+		if err := s.runStep(seq, idx, c, prefix, nil); err != nil {
+			errs = append(errs, err)
 		}
+	}
+	return errors.Join(errs...)
+}
+
+// runStep executes a single entry in a list of calls, which may refer to an entire imported sequence
+func (s *sequenceRun) runStep(seq *Sequence, idx int, c Call, prefix string, params map[string]any) error {
+	var callParams map[string]any
+	call, defining, withs, err := resolveImportedCall(seq, c)
+	if err == nil {
+		callParams, err = s.evaluateWiths(params, withs)
+		err = atPosition(c.pos, err)
+	}
+
+	name := call.Name
+	switch {
+	case err != nil:
+		name = c.Name
+	case call.FromImport != nil && name == "":
+		name = call.FromImport.Name
+	}
+	if name == "" {
+		name = fmt.Sprintf("call_%v", idx)
+	}
+	name = prefix + name
 
-		result, err := exec.Execute(call)
+	if err != nil {
+		s.reporter.CallFinished(CallReport{Sequence: s.name, Name: name, Err: err})
+		return err
+	}
+
+	// A foreach on the step as written takes precedence over one on the imported call it refers to
+	foreach, foreachPath := c.Foreach, seq.path
+	if foreach == nil {
+		foreach, foreachPath = call.Foreach, defining.path
+	}
+	if foreach == nil {
+		return s.runResolvedStep(seq, c, call, defining, name, params, callParams)
+	}
+
+	items, err := s.foreachItems(*foreach, foreachPath, callParams)
+	if err != nil {
+		err = atPosition(c.pos, fmt.Errorf("error evaluating foreach: %w", err))
+		s.reporter.CallFinished(CallReport{Sequence: s.name, Name: name, Err: err})
+		return err
+	}
+	s.log.Info().Str("call", name).Int("items", len(items)).Msg("executing foreach")
+	for i, item := range items {
+		if s.ctx.Err() != nil {
+			return ErrInterrupted
+		}
+		// The item is only visible to this iteration, the same as a parameter
+		itemParams := map[string]any{foreach.GetAs(): item}
+		label, err := s.foreachLabel(*foreach, foreachPath, i, mergeParams(callParams, itemParams))
 		if err != nil {
-			return fmt.Errorf("error executing call %v: %w", name, err)
+			err = atPosition(c.pos, err)
+			s.reporter.CallFinished(CallReport{Sequence: s.name, Name: fmt.Sprintf("%v[%v]", name, i), Err: err})
+			return err
+		}
+		iterName := fmt.Sprintf("%v[%v]", name, label)
+		if err := s.runResolvedStep(seq, c, call, defining, iterName, mergeParams(params, itemParams), mergeParams(callParams, itemParams)); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+// runResolvedStep executes a step once its from-import has been resolved to the call it refers to,
+// unless the conditions of the step prevent it
+func (s *sequenceRun) runResolvedStep(seq *Sequence, c Call, call Call, defining *Sequence, name string, params map[string]any, callParams map[string]any) error {
+	// Conditions are checked on the step as written, then on the imported call it refers to
+	skipReason, err := s.skipReason(c, seq.path, params)
+	if err == nil && skipReason == "" && c.FromImport != nil && call.FromImport == nil {
+		skipReason, err = s.skipReason(call, defining.path, callParams)
+	}
+	if err != nil {
+		s.reporter.CallFinished(CallReport{Sequence: s.name, Name: name, Err: err})
+		return err
+	}
+	if skipReason != "" {
+		s.log.Info().Str("call", name).Str("reason", skipReason).Msg("skipping call")
+		s.reporter.CallFinished(CallReport{Sequence: s.name, Name: name, Skipped: true, SkipReason: skipReason})
+		return nil
+	}
+
+	if call.FromImport != nil {
+		// The call refers to an entire imported sequence, run its calls as a single step
+		s.log.Info().Str("step", name).Msg("executing imported sequence")
+		imported := defining.imported[call.FromImport.Name]
+		return s.runCalls(imported, imported.Calls, name+"/", callParams)
+	}
 
-		wantStatus := call.WantStatus
-		if wantStatus == 0 && call.GetType() == RequestTypeHttp {
-			wantStatus = http.StatusOK
+	return s.runCall(defining, call, name, callParams)
+}
+
+// skipReason evaluates the run-if and skip-if conditions of the call, returning why it should be
+// skipped, or "" if it should be executed
+func (s *sequenceRun) skipReason(call Call, seqPath string, params map[string]any) (string, error) {
+	if call.RunIf != "" {
+		holds, err := s.evaluateCondition(call.RunIf, seqPath, params)
+		if err != nil {
+			return "", atPosition(call.pos, fmt.Errorf("error evaluating run-if: %w", err))
 		}
+		if !holds {
+			return fmt.Sprintf("run-if '%v' is not met", call.RunIf), nil
+		}
+	}
+	if call.SkipIf != "" {
+		holds, err := s.evaluateCondition(call.SkipIf, seqPath, params)
+		if err != nil {
+			return "", atPosition(call.pos, fmt.Errorf("error evaluating skip-if: %w", err))
+		}
+		if holds {
+			return fmt.Sprintf("skip-if '%v' is met", call.SkipIf), nil
+		}
+	}
+	return "", nil
+}
+
+// evaluateCondition expands the condition as a template, then evaluates the result as jq against the
+// variables. The condition holds unless the result is false or null
+func (s *sequenceRun) evaluateCondition(cond string, seqPath string, params map[string]any) (bool, error) {
+	data := s.templateData(params)
+	var expanded string
+	if err := s.renderTemplate(cond, &expanded, seqPath, data); err != nil {
+		return false, err
+	}
+	value, err := s.executeJQ(data, expanded)
+	if err != nil {
+		return false, err
+	}
+	return value != nil && value != false, nil
+}
+
+// runCall executes a single call defined by seq, exporting any requested values from its result, and
+// reports the outcome
+func (s *sequenceRun) runCall(seq *Sequence, call Call, name string, params map[string]any) (err error) {
+	report := CallReport{
+		Sequence: s.name,
+		Name:     name,
+	}
+	start := time.Now()
+	defer func() {
+		report.Duration = time.Since(start)
+		report.Err = err
+		s.reporter.CallFinished(report)
+	}()
 
-		if result.StatusCode != wantStatus {
-			r.log.Error().Interface("body", result.Body).Msg("body")
-			r.log.Err(result.Error).Msg("error msg")
-			return fmt.Errorf("got incorrect status: want (%v) got (%v)", wantStatus, result.StatusCode)
+	s.log.Info().Str("call", name).Msg("executing call")
+	params, err = resolveParams(call, params, s.ctxVariables)
+	if err != nil {
+		return atPosition(call.pos, err)
+	}
+	evaluated, err := s.evaluateTemplate(call, seq.path, params)
+	if err != nil {
+		return atPosition(call.pos, err)
+	}
+	call = s.resolveCallPaths(evaluated, seq.path)
+
+	// Calls take any defaults from the sequence defining them first, then the sequence being run
+	layers := []*Sequence{seq}
+	if s.seq != seq {
+		layers = append(layers, s.seq)
+	}
+	for _, layer := range layers {
+		defaults, err := s.sequenceDefaults(layer, params)
+		if err != nil {
+			return atPosition(call.pos, err)
 		}
+		if call.GetType() == RequestTypeGrpc && !call.DescriptorSource.IsLocal() {
+			call.DescriptorSource = defaults.DescriptorSource
+		}
+		call.ConnectionOptions = call.ConnectionOptions.withDefaults(defaults.ConnectionOptions)
+	}
+	report.Call = &call
+
+	exec, err := s.getClient(call.Type)
+	if err != nil {
+		return fmt.Errorf("error creating request client: %w", err)
+	}
 
-		if call.Print {
+	result, asserts, err := s.executeCall(name, call, exec)
+	report.Result = result
+	report.Asserts = asserts
+	if result != nil && call.Print {
+		if text, ok := result.Body.(string); ok {
+			fmt.Fprint(s.output, text)
+		} else {
 			bodyBytes, err := json.MarshalIndent(result.Body, "", "   ")
 			if err != nil {
-				r.log.Err(err).Msg("error marshalling body for output")
+				s.log.Err(err).Msg("error marshalling body for output")
 				return err
 			}
-			fmt.Fprint(r.output, string(bodyBytes))
+			fmt.Fprint(s.output, string(bodyBytes))
 		}
+	}
+	if err != nil {
+		s.writeAssertDiffs(err)
+		return atPosition(call.pos, err)
+	}
 
-		for _, exp := range call.Exports {
-			value, err := r.executeJQString(result.Body, exp.JQ)
-			if err != nil {
-				return err
+	for _, exp := range call.Exports {
+		value, err := s.executeJQ(result.JQInput(exp.Input), exp.JQ)
+		if err != nil {
+			return atPosition(exp.pos, fmt.Errorf("error exporting %v: %w", exp.As, err))
+		}
+		value = normalizeNumbers(value)
+		s.ctxVariables[exp.As] = value
+		s.exports[exp.As] = value
+		if report.Exports == nil {
+			report.Exports = make(map[string]any)
+		}
+		report.Exports[exp.As] = value
+	}
+
+	return nil
+}
+
+// sequenceDefaults returns the connection & descriptor defaults of seq, expanded and with their paths
+// resolved relative to seq, as a call holding nothing else
+func (s *sequenceRun) sequenceDefaults(seq *Sequence, params map[string]any) (Call, error) {
+	var defaults Call
+	layer := Call{DescriptorSource: seq.DescriptorSource, ConnectionOptions: seq.ConnectionOptions}
+	if err := s.renderTemplate(layer, &defaults, seq.path, s.templateData(params)); err != nil {
+		return Call{}, fmt.Errorf("error evaluating defaults of %v: %w", seq.file, err)
+	}
+	return s.resolveCallPaths(defaults, seq.path), nil
+}
+
+// executeCall executes the call, re-issuing it as directed by its retry block, and checks the final
+// result against the expectations of the call
+func (s *sequenceRun) executeCall(name string, call Call, exec Executor) (*ExecuteResult, []AssertResult, error) {
+	if call.Retry == nil {
+		result, err := exec.Execute(s.ctx, call)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error executing call %v: %w", name, err)
+		}
+		asserts, err := s.checkResult(name, call, result)
+		return result, asserts, err
+	}
+
+	attempts := call.Retry.GetAttempts()
+	interval := call.Retry.GetInterval()
+	for attempt := 1; ; attempt++ {
+		var asserts []AssertResult
+		result, err := exec.Execute(s.ctx, call)
+		if err != nil {
+			err = fmt.Errorf("error executing call %v: %w", name, err)
+		} else if call.Retry.Until != nil {
+			err = s.checkRetryUntil(name, call.Retry.Until, result)
+		} else {
+			asserts, err = s.checkResult(name, call, result)
+		}
+
+		if err == nil {
+			s.log.Info().Str("call", name).Int("attempt", attempt).Msg("retry condition met")
+			if call.Retry.Until != nil {
+				asserts, err = s.checkResult(name, call, result)
 			}
-			r.ctxVariables[exp.As] = value
+			return result, asserts, err
 		}
 
-		for _, ass := range call.Asserts {
-			value, err := r.executeJQ(result.Body, ass.JQ)
-			if err != nil {
-				return err
+		if attempt >= attempts {
+			return result, asserts, fmt.Errorf("call %v did not succeed after %v attempts: %w", name, attempts, err)
+		}
+
+		s.log.Warn().
+			Str("call", name).
+			Int("attempt", attempt).
+			Int("attempts", attempts).
+			AnErr("reason", err).
+			Dur("retry-in", interval).
+			Msg("retry condition not met")
+		if err := s.sleep(s.ctx, interval); err != nil {
+			return result, asserts, fmt.Errorf("call %v: %w", name, err)
+		}
+		interval = time.Duration(float64(interval) * call.Retry.GetBackoff())
+	}
+}
+
+// sleepContext waits for d to pass, returning early with ErrInterrupted if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ErrInterrupted
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (s *sequenceRun) checkResult(name string, call Call, result *ExecuteResult) ([]AssertResult, error) {
+	wantStatus := call.WantStatus
+	if wantStatus == 0 && call.GetType() == RequestTypeHttp {
+		wantStatus = http.StatusOK
+	}
+
+	if result.StatusCode != wantStatus {
+		s.log.Error().Interface("body", result.Body).Msg("body")
+		s.log.Err(result.Error).Msg("error msg")
+		return nil, fmt.Errorf("got incorrect status: want (%v) got (%v)", wantStatus, result.StatusCode)
+	}
+
+	return s.checkAsserts(name, call.Asserts, result)
+}
+
+func (s *sequenceRun) checkRetryUntil(name string, until *RetryUntil, result *ExecuteResult) error {
+	if len(until.Status) > 0 {
+		found := false
+		for _, status := range until.Status {
+			if result.StatusCode == status {
+				found = true
+				break
 			}
-			if diff := cmp.Diff(ass.Expected, value); diff != "" {
-				r.log.Error().Msg("failed assertion")
-				fmt.Println(diff)
-				return fmt.Errorf("failed assert")
+		}
+		if !found {
+			return fmt.Errorf("got status %v, waiting for one of %v", result.StatusCode, until.Status)
+		}
+	}
+
+	_, err := s.checkAsserts(name, until.Asserts, result)
+	return err
+}
+
+// checkAsserts evaluates every assert against the result, returning the outcome of each, along with an
+// *AssertionsError describing all of the failures, if there were any
+func (s *sequenceRun) checkAsserts(name string, asserts []Assert, result *ExecuteResult) ([]AssertResult, error) {
+	results := make([]AssertResult, 0, len(asserts))
+	var failures []*AssertError
+	for _, ass := range asserts {
+		value, err := s.executeJQ(result.JQInput(ass.Input), ass.JQ)
+		if err != nil {
+			assErr := &AssertError{
+				Op:       ass.GetOp(),
+				JQ:       ass.JQ,
+				Expected: ass.Expected,
+				Reason:   fmt.Sprintf("error executing jq: %v", err),
+				Pos:      ass.pos,
 			}
+			failures = append(failures, assErr)
+			results = append(results, AssertResult{Assert: ass, Err: assErr})
+			continue
 		}
+
+		assResult := AssertResult{Assert: ass, Actual: value}
+		if err := evaluateAssert(ass, value); err != nil {
+			s.log.Error().Str("call", name).Str("jq", ass.JQ).Stringer("op", ass.GetOp()).Interface("actual", value).Msg("failed assertion")
+			var assErr *AssertError
+			if !errors.As(err, &assErr) {
+				return results, atPosition(ass.pos, err)
+			}
+			assErr.Pos = ass.pos
+			failures = append(failures, assErr)
+			assResult.Err = assErr
+		}
+		results = append(results, assResult)
 	}
 
-	return nil
+	if len(failures) == 0 {
+		return results, nil
+	}
+	return results, &AssertionsError{Call: name, Failures: failures}
+}
+
+// writeAssertDiffs writes the diffs of any failed equality assertions to the output
+func (s *sequenceRun) writeAssertDiffs(err error) {
+	if s.output == nil {
+		return
+	}
+	fmt.Fprint(s.output, assertDiffs(err))
 }
 
 //nolint:ireturn
@@ -188,6 +883,50 @@ func (r *Runner) resolvePath(seqPath string, path string) string {
 	}
 }
 
+// resolveCallPaths resolves any file paths given in the call relative to the sequence defining it. Proto
+// files are the exception when import paths are given, as they're relative to the import paths instead
+func (r *Runner) resolveCallPaths(call Call, seqPath string) Call {
+	resolveAll := func(paths []string) []string {
+		if paths == nil {
+			return nil
+		}
+		resolved := make([]string, 0, len(paths))
+		for _, path := range paths {
+			resolved = append(resolved, r.resolvePath(seqPath, path))
+		}
+		return resolved
+	}
+	if call.TLS != nil {
+		tlsConf := *call.TLS
+		for _, path := range []*string{&tlsConf.CAFile, &tlsConf.CertFile, &tlsConf.KeyFile} {
+			if *path != "" {
+				*path = r.resolvePath(seqPath, *path)
+			}
+		}
+		call.TLS = &tlsConf
+	}
+	call.Protosets = resolveAll(call.Protosets)
+	call.ImportPaths = resolveAll(call.ImportPaths)
+	if len(call.ImportPaths) == 0 {
+		call.ProtoFiles = resolveAll(call.ProtoFiles)
+	}
+
+	if call.BodyFile != "" {
+		call.BodyFile = r.resolvePath(seqPath, call.BodyFile)
+	}
+	if call.BodyMultipart != nil {
+		parts := make([]MultipartPart, len(call.BodyMultipart))
+		copy(parts, call.BodyMultipart)
+		for i := range parts {
+			if parts[i].File != "" {
+				parts[i].File = r.resolvePath(seqPath, parts[i].File)
+			}
+		}
+		call.BodyMultipart = parts
+	}
+	return call
+}
+
 func (r *Runner) readFile(seqPath string, path string) ([]byte, error) {
 	fileBytes, err := os.ReadFile(r.resolvePath(seqPath, path))
 	if err != nil {
@@ -196,8 +935,18 @@ func (r *Runner) readFile(seqPath string, path string) ([]byte, error) {
 	return fileBytes, nil
 }
 
-func (r *Runner) genFuncs(seqPath string) template.FuncMap {
-	return template.FuncMap{
+// genFuncs returns the functions available to templates, those from Sprig along with our own. Random
+// values are taken from rng, or a randomly seeded source if it's nil
+func (r *Runner) genFuncs(seqPath string, rng *rand.Rand) template.FuncMap {
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec
+	}
+
+	funcs := sprig.TxtFuncMap()
+	for name, fn := range extraFuncs(rng) {
+		funcs[name] = fn
+	}
+	for name, fn := range (template.FuncMap{
 		"env": func(key string) (string, error) {
 			val, ok := os.LookupEnv(key)
 			if !ok {
@@ -219,64 +968,165 @@ func (r *Runner) genFuncs(seqPath string) template.FuncMap {
 			}
 			return string(fBytes), nil
 		},
+		// Replaced when expanding templates, as it depends on the string being expanded
+		"typed": func(any) (string, error) {
+			return "", ErrTypedValue
+		},
+	}) {
+		funcs[name] = fn
 	}
+	return funcs
 }
 
-func (r *Runner) resolveImports(seq *Sequence) error {
-	r.log.Debug().Msg("resolving imports")
-	for name, path := range seq.Imports {
-		r.log.Debug().Str("import", name).Msg("parsing imported sequence")
-		impSeq, err := r.parser.ParseSingleSequence(r.resolvePath(seq.path, path))
-		if err != nil {
-			return fmt.Errorf("error parsing imported sequence '%v': %w", path, err)
-		}
+func (s *sequenceRun) evaluateTemplate(call Call, seqPath string, params map[string]any) (Call, error) {
+	var newCall Call
+	if err := s.renderTemplate(call, &newCall, seqPath, s.templateData(params)); err != nil {
+		return Call{}, err
+	}
+	// Positions aren't marshalled, so carry them over from the original
+	newCall.copyPositions(call)
 
-		if impSeq.Imports != nil {
-			return fmt.Errorf("import %v contains imports. Only one level of nesting supported", name)
-		}
+	return newCall, nil
+}
 
-		seq.importedCalls[name] = make(map[string]Call)
-		for _, call := range impSeq.Calls {
-			seq.importedCalls[name][call.Name] = call
-		}
+// renderTemplate marshals in to YAML, expands each string within it as a template against data, and
+// unmarshals the result into out
+func (s *sequenceRun) renderTemplate(in any, out any, seqPath string, data map[string]any) error {
+	var node yaml.Node
+	if err := node.Encode(in); err != nil {
+		s.log.Err(err).Msg("error marshalling call")
+		return err
+	}
+
+	if err := s.expandNode(&node, false, seqPath, data); err != nil {
+		s.log.Err(err).Msg("error performing substitutions")
+		return err
+	}
+
+	if err := node.Decode(out); err != nil {
+		s.log.Err(err).Msg("marshalling back to yaml")
+		return err
 	}
 	return nil
 }
 
-func (r *Runner) evaluateTemplate(call Call, seqPath string) (Call, error) {
-	callBytes, err := yaml.Marshal(call)
-	if err != nil {
-		r.log.Err(err).Msg("error marshalling call")
-		return Call{}, err
+// expandNode expands every string within node as a template. A string holding nothing but a value passed
+// through typed is replaced by that value, keeping its type
+func (s *sequenceRun) expandNode(node *yaml.Node, isKey bool, seqPath string, data map[string]any) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := s.expandNode(child, false, seqPath, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	case yaml.MappingNode:
+		for i, child := range node.Content {
+			if err := s.expandNode(child, i%2 == 0, seqPath, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	case yaml.ScalarNode:
+	default:
+		return nil
 	}
 
-	funcs := r.genFuncs(seqPath)
+	if !isTemplateNode(node) {
+		return nil
+	}
 
-	t, err := template.New("").Funcs(funcs).Parse(string(callBytes))
-	if err != nil {
-		r.log.Err(err).Msg("error parsing call as template")
-		return Call{}, err
+	var typed []any
+	funcs := s.genFuncs(seqPath, s.rand)
+	funcs["typed"] = func(value any) string {
+		typed = append(typed, value)
+		return fmt.Sprintf(typedPlaceholder, len(typed)-1)
 	}
 
+	t, err := template.New("").Funcs(funcs).Parse(node.Value)
+	if err != nil {
+		return err
+	}
+	for _, tmpl := range t.Templates() {
+		requireTextValues(tmpl.Tree.Root)
+	}
 	var buf bytes.Buffer
-	if err := t.Execute(&buf, r.ctxVariables); err != nil {
-		r.log.Err(err).Msg("error performing substitutions")
-		return Call{}, err
+	if err := t.Execute(&buf, data); err != nil {
+		return err
 	}
 
-	var newCall Call
-	if err := yaml.Unmarshal(buf.Bytes(), &newCall); err != nil {
-		r.log.Err(err).Msg("marshalling back to yaml")
-		return Call{}, err
+	if len(typed) == 0 {
+		node.Value = buf.String()
+		return nil
+	}
+	if isKey || len(typed) > 1 || buf.String() != fmt.Sprintf(typedPlaceholder, 0) {
+		return fmt.Errorf("%w: %v", ErrTypedValue, node.Value)
+	}
+	var typedNode yaml.Node
+	if err := typedNode.Encode(typed[0]); err != nil {
+		return fmt.Errorf("unable to use %T as a typed value: %w", typed[0], err)
 	}
+	*node = typedNode
+	return nil
+}
 
-	return newCall, nil
+// isTemplateNode reports if node is a string that's expanded as a template
+func isTemplateNode(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str" && strings.Contains(node.Value, "{{")
 }
 
-func (r *Runner) executeJQ(body any, jq string) (any, error) {
+// mergeParams returns the parameters of both, with those of extra taking precedence
+func mergeParams(params map[string]any, extra map[string]any) map[string]any {
+	merged := make(map[string]any, len(params)+len(extra))
+	for k, v := range params {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+// templateData returns the variables available to templates, with any parameters given to the call
+// taking precedence
+func (s *sequenceRun) templateData(params map[string]any) map[string]any {
+	if len(params) == 0 {
+		return s.ctxVariables
+	}
+	data := make(map[string]any, len(s.ctxVariables)+len(params))
+	for k, v := range s.ctxVariables {
+		data[k] = v
+	}
+	for k, v := range params {
+		data[k] = v
+	}
+	return data
+}
+
+// evaluateWiths expands the parameters given by each from-import in turn, each able to refer to those
+// given before it, returning them merged on top of params
+func (s *sequenceRun) evaluateWiths(params map[string]any, withs []importWith) (map[string]any, error) {
+	merged := make(map[string]any, len(params))
+	for k, v := range params {
+		merged[k] = v
+	}
+	for _, w := range withs {
+		var with map[string]any
+		if err := s.renderTemplate(w.with, &with, w.seq.path, s.templateData(merged)); err != nil {
+			return nil, fmt.Errorf("error evaluating with: %w", err)
+		}
+		for k, v := range with {
+			merged[k] = v
+		}
+	}
+	return merged, nil
+}
+
+func (s *sequenceRun) executeJQ(body any, jq string) (any, error) {
 	query, err := gojq.Parse(jq)
 	if err != nil {
-		r.log.Err(err).Str("jq", jq).Msg("error parsing jq query")
+		s.log.Err(err).Str("jq", jq).Msg("error parsing jq query")
 		return "", err
 	}
 
@@ -294,7 +1144,7 @@ func (r *Runner) executeJQ(body any, jq string) (any, error) {
 		}
 
 		if err, ok := val.(error); ok {
-			r.log.Err(err).Msg("error executing JQ")
+			s.log.Err(err).Msg("error executing JQ")
 			return "", err
 		}
 
@@ -304,10 +1154,28 @@ func (r *Runner) executeJQ(body any, jq string) (any, error) {
 	return outVal, nil
 }
 
-func (r *Runner) executeJQString(body any, jq string) (string, error) {
-	val, err := r.executeJQ(body, jq)
-	if err != nil {
-		return "", err
+// normalizeNumbers converts any whole numbers decoded from JSON as floats within value to ints, so they're
+// written without exponents or decimals when templated
+func normalizeNumbers(value any) any {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int(v)
+		}
+		return v
+	case map[string]any:
+		normalized := make(map[string]any, len(v))
+		for k, inner := range v {
+			normalized[k] = normalizeNumbers(inner)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(v))
+		for i, inner := range v {
+			normalized[i] = normalizeNumbers(inner)
+		}
+		return normalized
+	default:
+		return value
 	}
-	return val.(string), err
 }
@@ -1,8 +1,17 @@
 package internal
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -54,10 +63,10 @@ func TestRun(t *testing.T) {
 
 		ok := &ExecuteResult{StatusCode: 200}
 		mockEx := NewMockExecutor(t)
-		mockEx.EXPECT().Execute(call1).Return(ok, nil)
-		mockEx.EXPECT().Execute(call2).Return(ok, nil)
-		mockEx.EXPECT().Execute(call3).Return(ok, nil)
-		mockEx.EXPECT().Execute(call4).Return(ok, nil)
+		mockEx.EXPECT().Execute(mock.Anything, call1).Return(ok, nil)
+		mockEx.EXPECT().Execute(mock.Anything, call2).Return(ok, nil)
+		mockEx.EXPECT().Execute(mock.Anything, call3).Return(ok, nil)
+		mockEx.EXPECT().Execute(mock.Anything, call4).Return(ok, nil)
 
 		runner := NewRunner(RunnerOpts{
 			HttpExecutor: mockEx,
@@ -67,6 +76,52 @@ func TestRun(t *testing.T) {
 		err := runner.Run("./some/path")
 		require.NoError(t, err)
 	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{
+				"seqA.yaml": seqA,
+				"seqB.yaml": seqB,
+			},
+			nil,
+		)
+
+		ok := &ExecuteResult{StatusCode: 200}
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, call1).Return(ok, nil)
+		mockEx.EXPECT().Execute(mock.Anything, call2).Return(ok, nil)
+		mockEx.EXPECT().Execute(mock.Anything, call3).Return(ok, nil)
+		mockEx.EXPECT().Execute(mock.Anything, call4).Return(ok, nil)
+
+		logs := &bytes.Buffer{}
+		runner := NewRunner(RunnerOpts{
+			Logger:       zerolog.New(logs),
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+			Concurrency:  2,
+			LogOutput:    logs,
+		})
+
+		err := runner.Run("./some/path")
+		require.NoError(t, err)
+
+		// Each sequences logs should be written as an uninterrupted block
+		var order []string
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var event map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &event))
+			seq, ok := event["sequence"].(string)
+			if !ok {
+				// Logged for the run as a whole
+				continue
+			}
+			if len(order) == 0 || order[len(order)-1] != seq {
+				order = append(order, seq)
+			}
+		}
+		require.Len(t, order, 2)
+	})
 }
 
 func TestVariablePassing(t *testing.T) {
@@ -102,7 +157,7 @@ func TestVariablePassing(t *testing.T) {
 		mockParser.EXPECT().Parse("./some/path").Return(SequenceMap{"seqA.yaml": seqA}, nil)
 
 		mockEx := NewMockExecutor(t)
-		mockEx.EXPECT().Execute(call1).Return(
+		mockEx.EXPECT().Execute(mock.Anything, call1).Return(
 			&ExecuteResult{
 				StatusCode: 200,
 				Body: map[string]any{
@@ -113,13 +168,217 @@ func TestVariablePassing(t *testing.T) {
 			},
 			nil,
 		)
-		mockEx.EXPECT().Execute(transformCall2).Return(&ExecuteResult{StatusCode: 200}, nil)
+		mockEx.EXPECT().Execute(mock.Anything, transformCall2).Return(&ExecuteResult{StatusCode: 200}, nil)
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser: mockParser,
+		})
+
+		err := runner.Run("./some/path")
+		require.NoError(t, err)
+	})
+
+	t.Run("layered variables", func(t *testing.T) {
+		call := Call{
+			Name: "fetch",
+			Url:  "http://{{ .host }}/{{ .region }}/{{ .version }}",
+		}
+		transformCall := Call{
+			Name: "fetch",
+			Url:  "http://seq.api.com/cli-region/env-version",
+		}
+		seqA := Sequence{
+			Vars:  map[string]any{"host": "seq.api.com", "region": "seq-region"},
+			Calls: []Call{call},
+		}
+
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(SequenceMap{"seqA.yaml": seqA}, nil)
+
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, transformCall).Return(&ExecuteResult{StatusCode: 200}, nil)
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+			Variables: Variables{
+				Env:       map[string]any{"host": "env.api.com", "region": "env-region", "version": "env-version"},
+				Overrides: map[string]any{"region": "cli-region"},
+			},
+		})
+
+		err := runner.Run("./some/path")
+		require.NoError(t, err)
+	})
+
+	t.Run("happy jq response", func(t *testing.T) {
+		call1 := Call{
+			Name:       "create",
+			Url:        "http://some.api.com/create",
+			WantStatus: 201,
+			Exports: []Export{
+				{
+					JQ:    ".headers.location[0]",
+					As:    "location",
+					Input: JQInputResponse,
+				},
+			},
+			Asserts: []Assert{
+				{JQ: ".status", Expected: 201, Input: JQInputResponse},
+				{JQ: ".duration_ms", Op: AssertOpLt, Expected: 200, Input: JQInputResponse},
+				{JQ: ".body.id", Expected: "abc", Input: JQInputResponse},
+				{JQ: ".id", Expected: "abc"},
+			},
+		}
+		call2 := Call{
+			Name: "fetch",
+			Url:  "{{ .location }}",
+		}
+		transformCall2 := Call{
+			Name: "fetch",
+			Url:  "http://some.api.com/objects/abc",
+		}
+
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(SequenceMap{"seqA.yaml": {Calls: []Call{call1, call2}}}, nil)
+
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, call1).Return(
+			&ExecuteResult{
+				StatusCode: 201,
+				Headers:    map[string][]string{"Location": {"http://some.api.com/objects/abc"}},
+				Duration:   50 * time.Millisecond,
+				Body:       map[string]any{"id": "abc"},
+			},
+			nil,
+		)
+		mockEx.EXPECT().Execute(mock.Anything, transformCall2).Return(&ExecuteResult{StatusCode: 200}, nil)
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+		})
+
+		err := runner.Run("./some/path")
+		require.NoError(t, err)
+	})
+
+	t.Run("typed exports", func(t *testing.T) {
+		var executed []Call
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, call Call) (*ExecuteResult, error) {
+			executed = append(executed, call)
+			return &ExecuteResult{
+				StatusCode: 200,
+				Body: map[string]any{
+					"id":    float64(1234567),
+					"owner": map[string]any{"name": "bob", "age": float64(30)},
+				},
+			}, nil
+		})
 
 		runner := NewRunner(RunnerOpts{
 			HttpExecutor: mockEx,
+			Parser:       NewFSParser(FSParserOpts{}),
+		})
+
+		require.NoError(t, runner.Run("testdata/runner/typed.yaml"))
+		require.Len(t, executed, 2)
+		require.Equal(t, "http://some.api.com/users/1234567", executed[1].Url)
+		require.Equal(t, map[string]any{
+			"id":    1234567,
+			"owner": map[string]any{"name": "bob", "age": 30},
+			"tags":  []any{"a", "b"},
+			"name":  "bob",
+		}, executed[1].Body)
+	})
+
+	t.Run("typed within text", func(t *testing.T) {
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{"seqA.yaml": {
+				Vars:  map[string]any{"id": 5},
+				Calls: []Call{{Name: "use", Url: "http://some.api.com/{{ typed .id }}"}},
+			}},
+			nil,
+		)
+
+		runner := NewRunner(RunnerOpts{
+			Parser: mockParser,
+		})
+
+		err := runner.Run("./some/path")
+		require.ErrorIs(t, err, ErrTypedValue)
+	})
+
+	t.Run("map within text", func(t *testing.T) {
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{"seqA.yaml": {
+				Vars:  map[string]any{"owner": map[string]any{"name": "bob", "age": 30}},
+				Calls: []Call{{Name: "use", Url: "http://some.api.com/{{ if true }}{{ .owner }}{{ end }}"}},
+			}},
+			nil,
+		)
+
+		runner := NewRunner(RunnerOpts{
 			Parser: mockParser,
 		})
 
+		err := runner.Run("./some/path")
+		require.ErrorIs(t, err, ErrTextValue)
+		require.ErrorContains(t, err, "use typed to keep its type, or toJson to write it as JSON")
+	})
+
+	t.Run("isolated between concurrent sequences", func(t *testing.T) {
+		exporter := Call{
+			Name: "fetch",
+			Url:  "http://some.api.com/get",
+			Exports: []Export{
+				{
+					JQ: ".name",
+					As: "fooVar",
+				},
+			},
+		}
+		user := Call{
+			Name: "use",
+			Url:  "http://some.api.com/use",
+			Headers: map[string]string{
+				"name": "{{ .fooVar }}",
+			},
+		}
+		transformUser := Call{
+			Name: "use",
+			Url:  "http://some.api.com/use",
+			Headers: map[string]string{
+				"name": "<no value>",
+			},
+		}
+
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{
+				"seqA.yaml": {Calls: []Call{exporter}},
+				"seqB.yaml": {Calls: []Call{user}},
+			},
+			nil,
+		)
+
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, exporter).Return(
+			&ExecuteResult{StatusCode: 200, Body: map[string]any{"name": "fooNameActual"}},
+			nil,
+		)
+		mockEx.EXPECT().Execute(mock.Anything, transformUser).Return(&ExecuteResult{StatusCode: 200}, nil)
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+			Concurrency:  2,
+		})
+
 		err := runner.Run("./some/path")
 		require.NoError(t, err)
 	})
@@ -146,7 +405,7 @@ func TestAssertions(t *testing.T) {
 		)
 
 		mockEx := NewMockExecutor(t)
-		mockEx.EXPECT().Execute(call1).Return(
+		mockEx.EXPECT().Execute(mock.Anything, call1).Return(
 			&ExecuteResult{
 				StatusCode: 200,
 				Body: map[string]any{
@@ -166,4 +425,1046 @@ func TestAssertions(t *testing.T) {
 		err := runner.Run("./some/path")
 		require.NoError(t, err)
 	})
+
+	t.Run("every failure reported", func(t *testing.T) {
+		call1 := Call{
+			Name: "foo",
+			Url:  "http://some.api.com",
+			Asserts: []Assert{
+				{JQ: ".name", Expected: "baz"},
+				{JQ: ".count", Op: AssertOpGt, Expected: 1},
+				{JQ: ".count", Op: AssertOpLt, Expected: 1},
+			},
+		}
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{"seqA.yaml": Sequence{Calls: []Call{call1}}},
+			nil,
+		)
+
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, call1).Return(
+			&ExecuteResult{
+				StatusCode: 200,
+				Body:       map[string]any{"name": "bar", "count": float64(2)},
+			},
+			nil,
+		)
+
+		output := &bytes.Buffer{}
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+			Output:       output,
+		})
+
+		err := runner.Run("./some/path")
+		require.ErrorIs(t, err, ErrAssertFailed)
+
+		var assErrs *AssertionsError
+		require.ErrorAs(t, err, &assErrs)
+		require.Equal(t, "foo", assErrs.Call)
+		require.Len(t, assErrs.Failures, 2)
+		require.Equal(t, ".name", assErrs.Failures[0].JQ)
+		require.Equal(t, "bar", assErrs.Failures[0].Actual)
+		require.Equal(t, AssertOpLt, assErrs.Failures[1].Op)
+
+		require.Contains(t, output.String(), "foo: .name (-expected +actual)")
+	})
+
+	t.Run("positions", func(t *testing.T) {
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, mock.Anything).Return(
+			&ExecuteResult{StatusCode: 200, Body: map[string]any{"count": 3, "name": "foo"}},
+			nil,
+		)
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       NewFSParser(FSParserOpts{}),
+		})
+
+		err := runner.Run("testdata/runner/assert_positions.yaml")
+		require.ErrorContains(t, err, "testdata/runner/assert_positions.yaml:2:3: call fetch failed 1 assert(s): testdata/runner/assert_positions.yaml:8:5: failed assert: .count equal: expected 2, got 3")
+	})
+
+	t.Run("export and template positions", func(t *testing.T) {
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, mock.Anything).Return(
+			&ExecuteResult{StatusCode: 200, Body: map[string]any{"items": []any{"a", "b"}, "id": "abc"}},
+			nil,
+		)
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       NewFSParser(FSParserOpts{}),
+		})
+
+		err := runner.Run("testdata/runner/export_positions.yaml")
+		require.ErrorContains(t, err, "testdata/runner/export_positions.yaml:5:5: error exporting bad: jq resulted in more than 1 value")
+
+		err = runner.Run("testdata/runner/template_positions.yaml")
+		require.ErrorContains(t, err, "testdata/runner/template_positions.yaml:7:3: template:")
+	})
+}
+
+func TestSequenceOrdering(t *testing.T) {
+	seqFor := func(file string, dependsOn ...string) Sequence {
+		return Sequence{
+			DependsOn: dependsOn,
+			Calls:     []Call{{Name: file, Url: "http://some.api.com/" + file}},
+			file:      "root/" + file,
+			path:      "root",
+		}
+	}
+
+	recordOrder := func(mockEx *MockExecutor, order *[]string, failing ...string) {
+		var mu sync.Mutex
+		mockEx.EXPECT().Execute(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, call Call) (*ExecuteResult, error) {
+			mu.Lock()
+			*order = append(*order, call.Name)
+			mu.Unlock()
+			for _, f := range failing {
+				if f == call.Name {
+					return &ExecuteResult{StatusCode: 500}, nil
+				}
+			}
+			return &ExecuteResult{StatusCode: 200}, nil
+		})
+	}
+
+	t.Run("sorted by default", func(t *testing.T) {
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{
+				"c.yaml":     seqFor("c.yaml"),
+				"a.yaml":     seqFor("a.yaml"),
+				"sub/b.yaml": seqFor("sub/b.yaml"),
+				"b.yaml":     seqFor("b.yaml"),
+			},
+			nil,
+		)
+
+		var order []string
+		mockEx := NewMockExecutor(t)
+		recordOrder(mockEx, &order)
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+		})
+
+		err := runner.Run("./some/path")
+		require.NoError(t, err)
+		require.Equal(t, []string{"a.yaml", "b.yaml", "c.yaml", "sub/b.yaml"}, order)
+	})
+
+	t.Run("dependencies run first", func(t *testing.T) {
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{
+				"a.yaml":      seqFor("a.yaml", "setup.yaml"),
+				"b.yaml":      seqFor("b.yaml"),
+				"setup.yaml":  seqFor("setup.yaml", "z/base.yaml"),
+				"z/base.yaml": seqFor("z/base.yaml"),
+			},
+			nil,
+		)
+
+		var order []string
+		mockEx := NewMockExecutor(t)
+		recordOrder(mockEx, &order)
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+		})
+
+		err := runner.Run("./some/path")
+		require.NoError(t, err)
+		require.Equal(t, []string{"z/base.yaml", "setup.yaml", "a.yaml", "b.yaml"}, order)
+	})
+
+	t.Run("dependents of failures are skipped", func(t *testing.T) {
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{
+				"a.yaml":     seqFor("a.yaml", "setup.yaml"),
+				"b.yaml":     seqFor("b.yaml", "a.yaml"),
+				"c.yaml":     seqFor("c.yaml"),
+				"setup.yaml": seqFor("setup.yaml"),
+			},
+			nil,
+		)
+
+		var order []string
+		mockEx := NewMockExecutor(t)
+		recordOrder(mockEx, &order, "setup.yaml")
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+			Concurrency:  2,
+		})
+
+		err := runner.Run("./some/path")
+		require.ErrorIs(t, err, ErrDependencyNotMet)
+		require.ElementsMatch(t, []string{"setup.yaml", "c.yaml"}, order)
+	})
+
+	t.Run("cycles error", func(t *testing.T) {
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{
+				"a.yaml": seqFor("a.yaml", "b.yaml"),
+				"b.yaml": seqFor("b.yaml", "a.yaml"),
+			},
+			nil,
+		)
+
+		runner := NewRunner(RunnerOpts{
+			Parser: mockParser,
+		})
+
+		err := runner.Run("./some/path")
+		require.ErrorIs(t, err, ErrDependencyCycle)
+		require.ErrorContains(t, err, "a.yaml -> b.yaml -> a.yaml")
+	})
+
+	t.Run("unknown dependency errors", func(t *testing.T) {
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{"a.yaml": seqFor("a.yaml", "missing.yaml")},
+			nil,
+		)
+
+		runner := NewRunner(RunnerOpts{
+			Parser: mockParser,
+		})
+
+		err := runner.Run("./some/path")
+		require.ErrorIs(t, err, ErrUnknownDependency)
+	})
+}
+
+func TestRetry(t *testing.T) {
+	runWith := func(t *testing.T, call Call, results ...*ExecuteResult) ([]time.Duration, int, error) {
+		t.Helper()
+
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{"seqA.yaml": Sequence{Calls: []Call{call}}},
+			nil,
+		)
+
+		calls := 0
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, call).RunAndReturn(func(context.Context, Call) (*ExecuteResult, error) {
+			res := results[calls]
+			calls++
+			return res, nil
+		})
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       mockParser,
+		})
+		var sleeps []time.Duration
+		runner.sleep = func(_ context.Context, d time.Duration) error {
+			sleeps = append(sleeps, d)
+			return nil
+		}
+
+		err := runner.Run("./some/path")
+		return sleeps, calls, err
+	}
+
+	pending := &ExecuteResult{StatusCode: 200, Body: map[string]any{"state": "pending"}}
+	done := &ExecuteResult{StatusCode: 200, Body: map[string]any{"state": "done"}}
+
+	t.Run("retries until asserts pass", func(t *testing.T) {
+		call := Call{
+			Name: "poll",
+			Url:  "http://some.api.com/job",
+			Asserts: []Assert{
+				{JQ: ".state", Expected: "done"},
+			},
+			Retry: &Retry{
+				Attempts: 5,
+				Interval: 10 * time.Millisecond,
+				Backoff:  2,
+			},
+		}
+
+		sleeps, calls, err := runWith(t, call, pending, pending, done)
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+		require.Equal(t, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}, sleeps)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		call := Call{
+			Name: "poll",
+			Url:  "http://some.api.com/job",
+			Asserts: []Assert{
+				{JQ: ".state", Expected: "done"},
+			},
+			Retry: &Retry{
+				Attempts: 2,
+			},
+		}
+
+		sleeps, calls, err := runWith(t, call, pending, pending)
+		require.ErrorContains(t, err, "did not succeed after 2 attempts")
+		require.Equal(t, 2, calls)
+		require.Equal(t, []time.Duration{time.Second}, sleeps)
+	})
+
+	t.Run("interrupted while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, sleepContext(ctx, time.Hour), ErrInterrupted)
+		require.NoError(t, sleepContext(context.Background(), time.Millisecond))
+	})
+
+	t.Run("until status", func(t *testing.T) {
+		call := Call{
+			Name: "poll",
+			Url:  "http://some.api.com/job",
+			Retry: &Retry{
+				Until: &RetryUntil{
+					Status: []int{200},
+				},
+			},
+		}
+
+		notFound := &ExecuteResult{StatusCode: 404}
+		_, calls, err := runWith(t, call, notFound, notFound, done)
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("until met but call checks fail", func(t *testing.T) {
+		call := Call{
+			Name: "poll",
+			Url:  "http://some.api.com/job",
+			Asserts: []Assert{
+				{JQ: ".state", Expected: "done"},
+			},
+			Retry: &Retry{
+				Until: &RetryUntil{
+					Asserts: []Assert{
+						{JQ: ".state", Expected: "pending"},
+					},
+				},
+			},
+		}
+
+		_, calls, err := runWith(t, call, pending)
+		require.ErrorContains(t, err, "failed assert")
+		require.Equal(t, 1, calls)
+	})
+}
+
+func TestSequenceDescriptorSource(t *testing.T) {
+	grpcCall := Call{
+		Name: "check",
+		Type: RequestTypeGrpc,
+		Url:  "grpc.health.v1.Health/Check",
+	}
+	ownCall := Call{
+		Name: "own",
+		Type: RequestTypeGrpc,
+		Url:  "grpc.health.v1.Health/Check",
+		DescriptorSource: DescriptorSource{
+			Protosets: []string{"/abs/health.protoset"},
+		},
+	}
+
+	mockParser := NewMockParser(t)
+	mockParser.EXPECT().Parse("./some/path").Return(
+		SequenceMap{
+			"seqA.yaml": {
+				Calls: []Call{grpcCall, ownCall},
+				path:  "protos",
+				DescriptorSource: DescriptorSource{
+					ProtoFiles: []string{"health.proto"},
+				},
+			},
+		},
+		nil,
+	)
+
+	inherited := grpcCall
+	inherited.DescriptorSource = DescriptorSource{ProtoFiles: []string{"protos/health.proto"}}
+
+	mockEx := NewMockExecutor(t)
+	mockEx.EXPECT().Execute(mock.Anything, inherited).Return(&ExecuteResult{}, nil)
+	mockEx.EXPECT().Execute(mock.Anything, ownCall).Return(&ExecuteResult{}, nil)
+
+	runner := NewRunner(RunnerOpts{
+		GrpcExecutor: mockEx,
+		Parser:       mockParser,
+	})
+
+	err := runner.Run("./some/path")
+	require.NoError(t, err)
+}
+
+func TestGRPCConnectionFailure(t *testing.T) {
+	mockParser := NewMockParser(t)
+	mockParser.EXPECT().Parse("./some/path").Return(
+		SequenceMap{
+			"seqA.yaml": {
+				Calls: []Call{{
+					Name:        "check",
+					Type:        RequestTypeGrpc,
+					ServiceHost: "127.0.0.1:1",
+					Url:         "grpc.health.v1.Health/Check",
+					ConnectionOptions: ConnectionOptions{
+						TLS: &TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+					},
+				}},
+			},
+		},
+		nil,
+	)
+
+	reporter := &recordingReporter{}
+	runner := NewRunner(RunnerOpts{
+		GrpcExecutor: NewGRPCExecutor(GRPCExecutorOpts{DialTimeout: time.Second}),
+		Parser:       mockParser,
+		Reporter:     reporter,
+	})
+
+	err := runner.Run("./some/path")
+	require.Error(t, err)
+	require.Equal(t, []string{"check"}, reporter.failed)
+}
+
+// recordingExecutor records every call given to it, responding to each with body, other than those to
+// the urls in fail, which fail with a 500
+type recordingExecutor struct {
+	mu    sync.Mutex
+	calls []Call
+	body  any
+	fail  []string
+}
+
+func newRecordingExecutor(body any, fail ...string) *recordingExecutor {
+	return &recordingExecutor{body: body, fail: fail}
+}
+
+func (r *recordingExecutor) Execute(_ context.Context, call Call) (*ExecuteResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+	for _, url := range r.fail {
+		if call.Url == url {
+			return &ExecuteResult{StatusCode: 500}, nil
+		}
+	}
+	return &ExecuteResult{StatusCode: 200, Body: r.body}, nil
+}
+
+// urls returns the url of every call executed, in the order they were
+func (r *recordingExecutor) urls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	urls := make([]string, 0, len(r.calls))
+	for _, call := range r.calls {
+		urls = append(urls, call.Url)
+	}
+	return urls
+}
+
+type recordingReporter struct {
+	mu        sync.Mutex
+	calls     []string
+	skipped   []string
+	failed    []string
+	sequences []SequenceReport
+}
+
+func (r *recordingReporter) SequenceStarted(SequenceReport) {}
+
+func (r *recordingReporter) CallFinished(report CallReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, report.Name)
+	if report.Skipped {
+		r.skipped = append(r.skipped, report.Name)
+	}
+	if report.Err != nil {
+		r.failed = append(r.failed, report.Name)
+	}
+}
+
+func (r *recordingReporter) SequenceFinished(report SequenceReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sequences = append(r.sequences, report)
+}
+
+func (r *recordingReporter) Close() error { return nil }
+
+func TestImports(t *testing.T) {
+	t.Run("nested", func(t *testing.T) {
+		var executed []string
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, call Call) (*ExecuteResult, error) {
+			executed = append(executed, call.Url)
+			if call.Name == "login" {
+				require.Equal(t, "testdata/imports/lib/payload.json", call.BodyFile)
+			}
+			return &ExecuteResult{StatusCode: 200, Body: map[string]any{"token": "abc"}}, nil
+		})
+
+		reporter := &recordingReporter{}
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Reporter:     reporter,
+		})
+
+		require.NoError(t, runner.Run("testdata/imports/main.yaml"))
+		require.Equal(t, []string{
+			"http://main.api.com/login",
+			"http://main.api.com/login",
+			"http://main.api.com/eu/ping",
+			"http://main.api.com/v2/abc",
+		}, executed)
+		require.Equal(t, []string{"login", "setup/login", "setup/ping", "fetch"}, reporter.calls)
+	})
+
+	t.Run("defaults relative to their own sequence", func(t *testing.T) {
+		var executed []Call
+		mockGrpc := NewMockExecutor(t)
+		mockGrpc.EXPECT().Execute(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, call Call) (*ExecuteResult, error) {
+			executed = append(executed, call)
+			return &ExecuteResult{StatusCode: 0}, nil
+		}).Once()
+		mockHttp := NewMockExecutor(t)
+		mockHttp.EXPECT().Execute(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, call Call) (*ExecuteResult, error) {
+			executed = append(executed, call)
+			return &ExecuteResult{StatusCode: 200}, nil
+		}).Once()
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockHttp,
+			GrpcExecutor: mockGrpc,
+			Parser:       NewFSParser(FSParserOpts{}),
+		})
+
+		require.NoError(t, runner.Run("testdata/imports/defaults/main.yaml"))
+		require.Len(t, executed, 2)
+		require.Equal(t, []string{"testdata/imports/defaults/lib/health.protoset"}, executed[0].Protosets)
+		for _, call := range executed {
+			require.Equal(t, "testdata/imports/defaults/certs/ca.pem", call.TLS.CAFile)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		runner := NewRunner(RunnerOpts{
+			Parser: NewFSParser(FSParserOpts{}),
+		})
+
+		err := runner.Run("testdata/imports/cycle/a.yaml")
+		require.ErrorIs(t, err, ErrImportCycle)
+		require.ErrorContains(t, err, "testdata/imports/cycle/a.yaml -> testdata/imports/cycle/b.yaml -> testdata/imports/cycle/a.yaml")
+	})
+}
+
+func TestImportParams(t *testing.T) {
+	t.Run("with", func(t *testing.T) {
+		var executed []Call
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, call Call) (*ExecuteResult, error) {
+			executed = append(executed, call)
+			return &ExecuteResult{StatusCode: 200, Body: map[string]any{"token": "abc"}}, nil
+		})
+
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       NewFSParser(FSParserOpts{}),
+		})
+
+		require.NoError(t, runner.Run("testdata/params/main.yaml"))
+		require.Len(t, executed, 4)
+		require.Equal(t, map[string]any{"user": "root", "pass": "hunter2"}, executed[0].Body)
+		require.Equal(t, map[string]any{"user": "guest", "pass": "secret"}, executed[1].Body)
+		require.Equal(t, map[string]any{"user": "bulk", "pass": "hunter2"}, executed[2].Body)
+		// Parameters are scoped to the call they're given to
+		require.Equal(t, "http://some.api.com/abc/<no value>", executed[3].Url)
+	})
+
+	t.Run("missing required", func(t *testing.T) {
+		runner := NewRunner(RunnerOpts{
+			Parser: NewFSParser(FSParserOpts{}),
+		})
+
+		err := runner.Run("testdata/params/missing.yaml")
+		require.ErrorIs(t, err, ErrMissingParameter)
+		require.ErrorContains(t, err, "testdata/params/lib.yaml:2:3: missing required parameter: username")
+	})
+}
+
+func TestSetupTeardown(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		executor := newRecordingExecutor(map[string]any{"id": "abc"})
+		reporter := &recordingReporter{}
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Reporter:     reporter,
+		})
+
+		require.NoError(t, runner.Run("testdata/hooks/sequence.yaml"))
+		require.Equal(t, []string{
+			"http://some.api.com/create",
+			"http://some.api.com/get/abc",
+			"http://some.api.com/list",
+			"http://some.api.com/delete/abc",
+			"http://some.api.com/cleanup",
+		}, executor.urls())
+		require.Equal(t, []string{"setup/create", "get", "list", "teardown/delete", "teardown/cleanup"}, reporter.calls)
+	})
+
+	t.Run("call failure", func(t *testing.T) {
+		executor := newRecordingExecutor(map[string]any{"id": "abc"}, "http://some.api.com/get/abc")
+		reporter := &recordingReporter{}
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Reporter:     reporter,
+		})
+
+		err := runner.Run("testdata/hooks/sequence.yaml")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrTeardownFailed)
+		require.Equal(t, []string{
+			"http://some.api.com/create",
+			"http://some.api.com/get/abc",
+			"http://some.api.com/delete/abc",
+			"http://some.api.com/cleanup",
+		}, executor.urls())
+		require.Len(t, reporter.sequences, 1)
+		require.Error(t, reporter.sequences[0].Err)
+		require.NoError(t, reporter.sequences[0].TeardownErr)
+	})
+
+	t.Run("setup failure", func(t *testing.T) {
+		executor := newRecordingExecutor(map[string]any{"id": "abc"}, "http://some.api.com/create")
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+		})
+
+		err := runner.Run("testdata/hooks/sequence.yaml")
+		require.ErrorContains(t, err, "error during setup")
+		require.Equal(t, []string{
+			"http://some.api.com/create",
+			"http://some.api.com/delete/<no value>",
+			"http://some.api.com/cleanup",
+		}, executor.urls())
+	})
+
+	t.Run("teardown failure", func(t *testing.T) {
+		executor := newRecordingExecutor(map[string]any{"id": "abc"}, "http://some.api.com/delete/abc")
+		reporter := &recordingReporter{}
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Reporter:     reporter,
+		})
+
+		err := runner.Run("testdata/hooks/sequence.yaml")
+		require.ErrorIs(t, err, ErrTeardownFailed)
+		require.Len(t, executor.urls(), 5)
+		require.Len(t, reporter.sequences, 1)
+		require.NoError(t, reporter.sequences[0].Err)
+		require.Error(t, reporter.sequences[0].TeardownErr)
+	})
+
+	t.Run("interrupted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var executed []string
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, call Call) (*ExecuteResult, error) {
+			executed = append(executed, call.Url)
+			if call.Name == "get" {
+				cancel()
+			}
+			return &ExecuteResult{StatusCode: 200, Body: map[string]any{"id": "abc"}}, nil
+		})
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       NewFSParser(FSParserOpts{}),
+		})
+
+		err := runner.RunContext(ctx, "testdata/hooks/sequence.yaml")
+		require.ErrorIs(t, err, ErrInterrupted)
+		require.Equal(t, []string{
+			"http://some.api.com/create",
+			"http://some.api.com/get/abc",
+			"http://some.api.com/delete/abc",
+			"http://some.api.com/cleanup",
+		}, executed)
+	})
+
+	t.Run("interrupted during call", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var executed []string
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, mock.Anything).RunAndReturn(func(callCtx context.Context, call Call) (*ExecuteResult, error) {
+			executed = append(executed, call.Url)
+			switch call.Name {
+			case "get":
+				// Stand in for a call that only returns once it's given up on
+				cancel()
+				<-callCtx.Done()
+				return nil, callCtx.Err()
+			case "delete", "cleanup":
+				// Teardown must be given a context of its own, bounded by the teardown timeout
+				require.NoError(t, callCtx.Err())
+				_, ok := callCtx.Deadline()
+				require.True(t, ok)
+			}
+			return &ExecuteResult{StatusCode: 200, Body: map[string]any{"id": "abc"}}, nil
+		})
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: mockEx,
+			Parser:       NewFSParser(FSParserOpts{}),
+		})
+
+		err := runner.RunContext(ctx, "testdata/hooks/sequence.yaml")
+		require.ErrorIs(t, err, context.Canceled)
+		require.NotErrorIs(t, err, ErrTeardownFailed)
+		require.Equal(t, []string{
+			"http://some.api.com/create",
+			"http://some.api.com/get/abc",
+			"http://some.api.com/delete/abc",
+			"http://some.api.com/cleanup",
+		}, executed)
+	})
+
+	t.Run("teardown timeout", func(t *testing.T) {
+		var executed []string
+		mockEx := NewMockExecutor(t)
+		mockEx.EXPECT().Execute(mock.Anything, mock.Anything).RunAndReturn(func(callCtx context.Context, call Call) (*ExecuteResult, error) {
+			executed = append(executed, call.Url)
+			if call.Name == "delete" {
+				<-callCtx.Done()
+				return nil, callCtx.Err()
+			}
+			return &ExecuteResult{StatusCode: 200, Body: map[string]any{"id": "abc"}}, nil
+		})
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor:    mockEx,
+			Parser:          NewFSParser(FSParserOpts{}),
+			TeardownTimeout: 10 * time.Millisecond,
+		})
+
+		err := runner.Run("testdata/hooks/sequence.yaml")
+		require.ErrorIs(t, err, ErrTeardownFailed)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Len(t, executed, 5)
+	})
+
+	t.Run("global hooks", func(t *testing.T) {
+		executor := newRecordingExecutor(map[string]any{"id": "abc"})
+		reporter := &recordingReporter{}
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Reporter:     reporter,
+			Hooks:        "testdata/hooks/run/hooks.yaml",
+		})
+
+		require.NoError(t, runner.Run("testdata/hooks/run"))
+		require.Equal(t, []string{
+			"http://some.api.com/login",
+			"http://some.api.com/users/abc",
+			"http://some.api.com/logout/abc",
+		}, executor.urls())
+		names := make([]string, 0, len(reporter.sequences))
+		for _, seq := range reporter.sequences {
+			names = append(names, seq.Name)
+		}
+		require.Equal(t, []string{"(setup)", "users.yaml", "(teardown)"}, names)
+	})
+
+	t.Run("global hooks by absolute path", func(t *testing.T) {
+		hooks, err := filepath.Abs("testdata/hooks/run/hooks.yaml")
+		require.NoError(t, err)
+
+		executor := newRecordingExecutor(map[string]any{"id": "abc"})
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Hooks:        hooks,
+		})
+
+		require.NoError(t, runner.Run("./testdata/hooks/run"))
+		require.Equal(t, []string{
+			"http://some.api.com/login",
+			"http://some.api.com/users/abc",
+			"http://some.api.com/logout/abc",
+		}, executor.urls())
+	})
+
+	t.Run("global setup failure", func(t *testing.T) {
+		executor := newRecordingExecutor(map[string]any{"id": "abc"}, "http://some.api.com/login")
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Hooks:        "testdata/hooks/run/hooks.yaml",
+		})
+
+		err := runner.Run("testdata/hooks/run")
+		require.ErrorContains(t, err, "error during global setup")
+		require.Equal(t, []string{
+			"http://some.api.com/login",
+			"http://some.api.com/logout/<no value>",
+		}, executor.urls())
+	})
+}
+
+func TestConditions(t *testing.T) {
+	t.Run("run-if and skip-if", func(t *testing.T) {
+		executor := newRecordingExecutor(map[string]any{"id": "abc"})
+		reporter := &recordingReporter{}
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Reporter:     reporter,
+		})
+
+		require.NoError(t, runner.Run("testdata/runner/conditions.yaml"))
+		require.Equal(t, []string{
+			"http://some.api.com/create",
+			"http://some.api.com/staging",
+			"http://some.api.com/delete/abc",
+		}, executor.urls())
+		require.Equal(t, []string{"create", "staging-only", "prod-only", "delete", "recreate"}, reporter.calls)
+		require.Equal(t, []string{"prod-only", "recreate"}, reporter.skipped)
+		require.Empty(t, reporter.failed)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		runner := NewRunner(RunnerOpts{
+			Parser: NewFSParser(FSParserOpts{}),
+		})
+
+		err := runner.Run("testdata/runner/condition_error.yaml")
+		require.ErrorContains(t, err, "testdata/runner/condition_error.yaml:2:3: error evaluating skip-if")
+	})
+}
+
+func TestForeach(t *testing.T) {
+	t.Run("sources", func(t *testing.T) {
+		executor := newRecordingExecutor(nil)
+		reporter := &recordingReporter{}
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Reporter:     reporter,
+		})
+
+		require.NoError(t, runner.Run("testdata/foreach/main.yaml"))
+		require.Equal(t, []string{
+			"http://some.api.com/roles/admin",
+			"http://some.api.com/roles/viewer",
+			"http://some.api.com/upper/ADMIN",
+			"http://some.api.com/upper/VIEWER",
+			"http://some.api.com/users/1",
+			"http://some.api.com/users/2",
+			"http://some.api.com/json/1234567",
+			// Items are scoped to their iteration
+			"http://some.api.com/after/<no value>",
+		}, executor.urls())
+		require.Equal(t, map[string]any{"name": "bob"}, executor.calls[5].Body)
+		require.Equal(t, []string{
+			"role[0]", "role[1]", "upper[0]", "upper[1]", "csv[alice]", "csv[bob]", "json[0]", "json[1]", "after",
+		}, reporter.calls)
+		require.Equal(t, []string{"json[1]"}, reporter.skipped)
+	})
+
+	t.Run("not a list", func(t *testing.T) {
+		runner := NewRunner(RunnerOpts{
+			Parser: NewFSParser(FSParserOpts{}),
+		})
+
+		err := runner.Run("testdata/foreach/invalid.yaml")
+		require.ErrorIs(t, err, ErrForeachList)
+		require.ErrorContains(t, err, "testdata/foreach/invalid.yaml:4:3: error evaluating foreach")
+	})
+}
+
+func TestMatrix(t *testing.T) {
+	t.Run("combinations", func(t *testing.T) {
+		executor := newRecordingExecutor(map[string]any{"token": "abc"})
+		reporter := &recordingReporter{}
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Reporter:     reporter,
+		})
+
+		require.NoError(t, runner.Run("testdata/matrix"))
+		// Every combination starts with its own variables, so none skip the check
+		require.Equal(t, []string{
+			"http://some.api.com/v1/eu/check",
+			"http://some.api.com/v1/eu/login",
+			"http://some.api.com/v1/eu/logout/abc",
+			"http://some.api.com/v2/eu/check",
+			"http://some.api.com/v2/eu/login",
+			"http://some.api.com/v2/eu/logout/abc",
+			"http://some.api.com/v1/us/check",
+			"http://some.api.com/v1/us/login",
+			"http://some.api.com/v1/us/logout/abc",
+			"http://some.api.com/v2/us/check",
+			"http://some.api.com/v2/us/login",
+			"http://some.api.com/v2/us/logout/abc",
+			"http://some.api.com/after",
+		}, executor.urls())
+		names := make([]string, 0, len(reporter.sequences))
+		for _, seq := range reporter.sequences {
+			names = append(names, seq.Name)
+		}
+		require.Equal(t, []string{
+			"api.yaml[region=eu,version=v1]",
+			"api.yaml[region=eu,version=v2]",
+			"api.yaml[region=us,version=v1]",
+			"api.yaml[region=us,version=v2]",
+			"after.yaml",
+		}, names)
+	})
+
+	t.Run("failed combination", func(t *testing.T) {
+		executor := newRecordingExecutor(map[string]any{"token": "abc"}, "http://some.api.com/v2/eu/login")
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Concurrency:  4,
+		})
+
+		err := runner.Run("testdata/matrix")
+		require.ErrorContains(t, err, "error during sequence api.yaml[region=eu,version=v2]")
+		require.ErrorIs(t, err, ErrDependencyNotMet)
+		require.Len(t, executor.urls(), 11)
+		require.NotContains(t, executor.urls(), "http://some.api.com/after")
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		executor := newRecordingExecutor(map[string]any{"token": "abc"})
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       NewFSParser(FSParserOpts{}),
+			Variables:    Variables{Overrides: map[string]any{"region": "local"}},
+		})
+
+		require.NoError(t, runner.Run("testdata/matrix"))
+		require.Contains(t, executor.urls(), "http://some.api.com/v1/eu/check")
+		require.NotContains(t, executor.urls(), "http://some.api.com/v1/local/check")
+	})
+
+	t.Run("duplicate values", func(t *testing.T) {
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{"seqA.yaml": {
+				Matrix: map[string][]any{"id": {1, "1"}},
+				Calls:  []Call{{Name: "get", Url: "http://some.api.com/{{ .id }}"}},
+			}},
+			nil,
+		)
+
+		runner := NewRunner(RunnerOpts{
+			Parser: mockParser,
+		})
+
+		err := runner.Run("./some/path")
+		require.ErrorIs(t, err, ErrDuplicateMatrix)
+		require.ErrorContains(t, err, "matrix has duplicate values for id: 1")
+	})
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	templateRun := func(t *testing.T, seed int64, headers map[string]string) map[string]string {
+		t.Helper()
+		mockParser := NewMockParser(t)
+		mockParser.EXPECT().Parse("./some/path").Return(
+			SequenceMap{"seqA.yaml": {
+				Calls: []Call{{Name: "call", Url: "http://some.api.com/get", Headers: headers}},
+			}},
+			nil,
+		)
+
+		executor := newRecordingExecutor(nil)
+		runner := NewRunner(RunnerOpts{
+			HttpExecutor: executor,
+			Parser:       mockParser,
+			Seed:         seed,
+		})
+
+		require.NoError(t, runner.Run("./some/path"))
+		require.Len(t, executor.calls, 1)
+		return executor.calls[0].Headers
+	}
+
+	t.Run("seeded random values", func(t *testing.T) {
+		headers := map[string]string{
+			"uuid":   "{{ uuid }}",
+			"int":    "{{ randInt 10 20 }}",
+			"string": "{{ randString 12 }}",
+		}
+
+		first := templateRun(t, 42, headers)
+		require.Equal(t, first, templateRun(t, 42, headers))
+		require.NotEqual(t, first, templateRun(t, 43, headers))
+
+		require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[0-9a-f]{4}-[0-9a-f]{12}$`, first["uuid"])
+		require.Regexp(t, `^1[0-9]$`, first["int"])
+		require.Regexp(t, `^[a-zA-Z0-9]{12}$`, first["string"])
+	})
+
+	t.Run("functions", func(t *testing.T) {
+		t.Setenv("POKE_TEMPLATE_SET", "set")
+
+		got := templateRun(t, 0, map[string]string{
+			"sha256":     `{{ sha256 "abc" }}`,
+			"hmac":       `{{ hmac "key" "The quick brown fox jumps over the lazy dog" }}`,
+			"urlencode":  `{{ urlencode "a b&c" }}`,
+			"envOrSet":   `{{ envOr "POKE_TEMPLATE_SET" "fallback" }}`,
+			"envOrUnset": `{{ envOr "POKE_TEMPLATE_UNSET" "fallback" }}`,
+			"b64dec":     `{{ b64dec "aGVsbG8=" }}`,
+			"json":       `{{ dict "a" 1 | toJson }}`,
+			"fromJson":   `{{ (fromJson "{\"a\": \"b\"}").a }}`,
+			"default":    `{{ "" | default "fallback" }}`,
+			"date":       `{{ now | date "2006" | len }}`,
+		})
+		require.Equal(t, map[string]string{
+			"sha256":     "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+			"hmac":       "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+			"urlencode":  "a+b%26c",
+			"envOrSet":   "set",
+			"envOrUnset": "fallback",
+			"b64dec":     "hello",
+			"json":       `{"a":1}`,
+			"fromJson":   "b",
+			"default":    "fallback",
+			"date":       "4",
+		}, got)
+	})
+
+	t.Run("values written as text", func(t *testing.T) {
+		got := templateRun(t, 0, map[string]string{
+			"now": "{{ now }}",
+		})
+		require.Regexp(t, `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`, got["now"])
+	})
 }
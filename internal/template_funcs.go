@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/url"
+	"os"
+	"reflect"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/google/uuid"
+)
+
+// textValueFunc is the name of the function given every value written by a template, see requireTextValues
+const textValueFunc = "textValue"
+
+const randStringChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// sequenceRand returns the source of random values for a sequence. Each sequence gets its own, derived
+// from the seed of the run and its name, so they're reproducible no matter the order sequences run in
+func sequenceRand(seed int64, name string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return rand.New(rand.NewSource(seed ^ int64(h.Sum64()))) //nolint:gosec
+}
+
+// extraFuncs are the template functions added on top of those from Sprig. Any random values are taken
+// from rng, so they're reproducible with the same seed
+func extraFuncs(rng *rand.Rand) template.FuncMap {
+	return template.FuncMap{
+		"envOr": func(key string, fallback string) string {
+			if val, ok := os.LookupEnv(key); ok {
+				return val
+			}
+			return fallback
+		},
+		"uuid": func() (string, error) {
+			id, err := uuid.NewRandomFromReader(rng)
+			if err != nil {
+				return "", fmt.Errorf("error generating uuid: %w", err)
+			}
+			return id.String(), nil
+		},
+		"randInt": func(min int, max int) (int, error) {
+			if max <= min {
+				return 0, fmt.Errorf("randInt requires max (%v) to be greater than min (%v)", max, min)
+			}
+			return rng.Intn(max-min) + min, nil
+		},
+		"randString": func(length int) string {
+			out := make([]byte, length)
+			for i := range out {
+				out[i] = randStringChars[rng.Intn(len(randStringChars))]
+			}
+			return string(out)
+		},
+		"sha256": func(value string) string {
+			sum := sha256.Sum256([]byte(value))
+			return hex.EncodeToString(sum[:])
+		},
+		"hmac": func(key string, value string) string {
+			mac := hmac.New(sha256.New, []byte(key))
+			mac.Write([]byte(value))
+			return hex.EncodeToString(mac.Sum(nil))
+		},
+		"urlencode": url.QueryEscape,
+		textValueFunc: func(value any) (any, error) {
+			// Values that know how to write themselves, such as the time from now, are fine as text
+			if _, ok := value.(fmt.Stringer); ok {
+				return value, nil
+			}
+			switch reflect.ValueOf(value).Kind() {
+			case reflect.Map, reflect.Slice, reflect.Array:
+				return nil, fmt.Errorf("%w, got %T: use typed to keep its type, or toJson to write it as JSON", ErrTextValue, value)
+			default:
+				return value, nil
+			}
+		},
+	}
+}
+
+// requireTextValues passes every value written by the actions within node through textValueFunc, so that
+// maps and lists error rather than being written as Go syntax
+func requireTextValues(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			requireTextValues(child)
+		}
+	case *parse.ActionNode:
+		// Actions that only declare or assign variables don't write anything
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(textValueFunc).SetPos(n.Pos)},
+		})
+	case *parse.IfNode:
+		requireTextValues(n.List)
+		requireTextValues(n.ElseList)
+	case *parse.RangeNode:
+		requireTextValues(n.List)
+		requireTextValues(n.ElseList)
+	case *parse.WithNode:
+		requireTextValues(n.List)
+		requireTextValues(n.ElseList)
+	}
+}
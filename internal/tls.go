@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// buildTLSConfig builds the TLS configuration for a call, starting from the system cert pool
+func buildTLSConfig(conf *TLSConfig, skipVerify bool) (*tls.Config, error) {
+	certPool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("error getting system cert pool: %w", err)
+	}
+
+	tlsConf := &tls.Config{
+		RootCAs:            certPool,
+		InsecureSkipVerify: skipVerify, //nolint:gosec
+	}
+	if conf == nil {
+		return tlsConf, nil
+	}
+
+	if conf.CAFile != "" {
+		caBytes, err := os.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file: %w", err)
+		}
+		if !tlsConf.RootCAs.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("no certificates found in CA file %v", conf.CAFile)
+		}
+	}
+
+	if conf.CertFile != "" || conf.KeyFile != "" {
+		if conf.CertFile == "" || conf.KeyFile == "" {
+			return nil, fmt.Errorf("cert-file and key-file must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+
+	tlsConf.ServerName = conf.ServerName
+
+	switch conf.MinVersion {
+	case "":
+	case "1.0":
+		tlsConf.MinVersion = tls.VersionTLS10
+	case "1.1":
+		tlsConf.MinVersion = tls.VersionTLS11
+	case "1.2":
+		tlsConf.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsConf.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unknown TLS min-version '%v', try one of [1.0, 1.1, 1.2, 1.3]", conf.MinVersion)
+	}
+
+	return tlsConf, nil
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testPKI is a CA along with a server and client certificate signed by it, written out to files
+type testPKI struct {
+	CAFile         string
+	ClientCertFile string
+	ClientKeyFile  string
+	ServerTLS      *tls.Config
+}
+
+// newTestPKI generates a fresh CA, and certificates for a server named serverName and a client. The server
+// config requires clients to present a certificate signed by the CA
+func newTestPKI(t *testing.T, serverName string) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "poke test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage, dnsNames ...string) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "poke test"},
+			DNSNames:     dnsNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	writePEM := func(name string, typ string, der []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600))
+		return path
+	}
+
+	client := issue(2, x509.ExtKeyUsageClientAuth)
+	clientKeyDER, err := x509.MarshalPKCS8PrivateKey(client.PrivateKey)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+
+	return testPKI{
+		CAFile:         writePEM("ca.pem", "CERTIFICATE", caDER),
+		ClientCertFile: writePEM("client.pem", "CERTIFICATE", client.Certificate[0]),
+		ClientKeyFile:  writePEM("client-key.pem", "PRIVATE KEY", clientKeyDER),
+		ServerTLS: &tls.Config{
+			Certificates: []tls.Certificate{issue(3, x509.ExtKeyUsageServerAuth, serverName)},
+			ClientCAs:    pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		},
+	}
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	pki := newTestPKI(t, "poke.test")
+
+	t.Run("defaults", func(t *testing.T) {
+		conf, err := buildTLSConfig(nil, false)
+		require.NoError(t, err)
+		require.NotNil(t, conf.RootCAs)
+		require.False(t, conf.InsecureSkipVerify)
+	})
+
+	t.Run("full", func(t *testing.T) {
+		conf, err := buildTLSConfig(&TLSConfig{
+			CAFile:     pki.CAFile,
+			CertFile:   pki.ClientCertFile,
+			KeyFile:    pki.ClientKeyFile,
+			ServerName: "poke.test",
+		}, true)
+		require.NoError(t, err)
+		require.Len(t, conf.Certificates, 1)
+		require.Equal(t, "poke.test", conf.ServerName)
+		require.True(t, conf.InsecureSkipVerify)
+	})
+
+	t.Run("cert without key", func(t *testing.T) {
+		_, err := buildTLSConfig(&TLSConfig{CertFile: pki.ClientCertFile}, false)
+		require.ErrorContains(t, err, "must be given together")
+	})
+
+	t.Run("missing CA", func(t *testing.T) {
+		_, err := buildTLSConfig(&TLSConfig{CAFile: "does-not-exist.pem"}, false)
+		require.ErrorContains(t, err, "error reading CA file")
+	})
+
+	t.Run("min version", func(t *testing.T) {
+		conf, err := buildTLSConfig(&TLSConfig{MinVersion: "1.2"}, false)
+		require.NoError(t, err)
+		require.Equal(t, uint16(tls.VersionTLS12), conf.MinVersion)
+
+		_, err = buildTLSConfig(&TLSConfig{MinVersion: "1.4"}, false)
+		require.ErrorContains(t, err, "unknown TLS min-version")
+	})
+}
@@ -1,5 +1,11 @@
 package internal
 
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
 //go:generate go-enum --file $GOFILE --marshal --names
 
 /*
@@ -10,20 +16,72 @@ grpc
 */
 type RequestType string
 
+/*
+ENUM(
+equal
+not-equal
+contains
+matches
+gt
+gte
+lt
+lte
+length
+exists
+not-exists
+type
+one-of
+)
+*/
+type AssertOp string
+
+/*
+ENUM(
+body
+response
+)
+*/
+type JQInput string
+
+/*
+ENUM(
+json
+text
+)
+*/
+type ResponseFormat string
+
 type Call struct {
-	Name        string            `yaml:"name,omitempty"`
-	Type        RequestType       `yaml:"type,omitempty"`
-	Body        map[string]any    `yaml:"body,omitempty"`
-	Headers     map[string]string `yaml:"headers,omitempty"`
-	ServiceHost string            `yaml:"service-host,omitempty"`
-	Url         string            `yaml:"url,omitempty"`
-	Method      string            `yaml:"method,omitempty"`
-	WantStatus  int               `yaml:"want-status,omitempty"`
-	Exports     []Export          `yaml:"exports,omitempty"`
-	Asserts     []Assert          `yaml:"asserts,omitempty"`
-	Print       bool              `yaml:"print,omitempty"`
-	SkipVerify  bool              `yaml:"skip-verify,omitempty"`
-	FromImport  *ImportedCall     `yaml:"from-import,omitempty"`
+	Name           string            `yaml:"name,omitempty"`
+	Type           RequestType       `yaml:"type,omitempty"`
+	Body           any               `yaml:"body,omitempty"`
+	BodyRaw        string            `yaml:"body-raw,omitempty"`
+	BodyForm       map[string]string `yaml:"body-form,omitempty"`
+	BodyMultipart  []MultipartPart   `yaml:"body-multipart,omitempty"`
+	BodyFile       string            `yaml:"body-file,omitempty"`
+	Headers        map[string]string `yaml:"headers,omitempty"`
+	ServiceHost    string            `yaml:"service-host,omitempty"`
+	Url            string            `yaml:"url,omitempty"`
+	Method         string            `yaml:"method,omitempty"`
+	WantStatus     int               `yaml:"want-status,omitempty"`
+	Exports        []Export          `yaml:"exports,omitempty"`
+	Asserts        []Assert          `yaml:"asserts,omitempty"`
+	Print          bool              `yaml:"print,omitempty"`
+	SkipVerify     bool              `yaml:"skip-verify,omitempty"`
+	FromImport     *ImportedCall     `yaml:"from-import,omitempty"`
+	Retry          *Retry            `yaml:"retry,omitempty"`
+	ResponseFormat ResponseFormat    `yaml:"response-format,omitempty"`
+	MaxMessages    int               `yaml:"max-messages,omitempty"`
+	StreamTimeout  time.Duration     `yaml:"stream-timeout,omitempty"`
+	Params         map[string]Param  `yaml:"params,omitempty"`
+	RunIf          string            `yaml:"run-if,omitempty"`
+	SkipIf         string            `yaml:"skip-if,omitempty"`
+	Foreach        *Foreach          `yaml:"foreach,omitempty"`
+
+	DescriptorSource  `yaml:",inline"`
+	ConnectionOptions `yaml:",inline"`
+
+	pos Position `yaml:"-"`
 }
 
 func (c *Call) GetType() RequestType {
@@ -33,25 +91,294 @@ func (c *Call) GetType() RequestType {
 	return c.Type
 }
 
+// GetMethod returns the http method of the call, defaulting to POST if a body is given, and GET otherwise
+func (c *Call) GetMethod() string {
+	if c.Method != "" {
+		return c.Method
+	}
+	if c.bodyCount() > 0 {
+		return http.MethodPost
+	}
+	return http.MethodGet
+}
+
+// copyPositions copies the positions of the call, and its exports & asserts, from another version of it
+func (c *Call) copyPositions(from Call) {
+	c.pos = from.pos
+	for i := range c.Exports {
+		if i < len(from.Exports) {
+			c.Exports[i].pos = from.Exports[i].pos
+		}
+	}
+	copyAssertPositions(c.Asserts, from.Asserts)
+	if c.Retry != nil && c.Retry.Until != nil && from.Retry != nil && from.Retry.Until != nil {
+		copyAssertPositions(c.Retry.Until.Asserts, from.Retry.Until.Asserts)
+	}
+}
+
+func copyAssertPositions(to []Assert, from []Assert) {
+	for i := range to {
+		if i < len(from) {
+			to[i].pos = from[i].pos
+		}
+	}
+}
+
+// bodyCount returns how many of the body options of the call are given
+func (c *Call) bodyCount() int {
+	count := 0
+	for _, isSet := range []bool{c.Body != nil, c.BodyRaw != "", c.BodyForm != nil, c.BodyMultipart != nil, c.BodyFile != ""} {
+		if isSet {
+			count++
+		}
+	}
+	return count
+}
+
+// MultipartPart is a single part of a multipart/form-data body, holding either a plain value or the
+// contents of a file
+type MultipartPart struct {
+	Name        string `yaml:"name,omitempty"`
+	Value       string `yaml:"value,omitempty"`
+	File        string `yaml:"file,omitempty"`
+	ContentType string `yaml:"content-type,omitempty"`
+}
+
+// DescriptorSource configures where the descriptors for grpc calls are loaded from. If nothing is given,
+// they are fetched from the server using reflection
+type DescriptorSource struct {
+	ProtoFiles  []string `yaml:"proto-files,omitempty"`
+	ImportPaths []string `yaml:"import-paths,omitempty"`
+	Protosets   []string `yaml:"protoset,omitempty"`
+}
+
+// IsLocal reports if descriptors should be loaded from local files instead of reflection
+func (d DescriptorSource) IsLocal() bool {
+	return len(d.ProtoFiles) > 0 || len(d.Protosets) > 0
+}
+
+func (d DescriptorSource) cacheKey() string {
+	return fmt.Sprintf("protoset=%v;import-paths=%v;proto-files=%v", d.Protosets, d.ImportPaths, d.ProtoFiles)
+}
+
+// TLSConfig customizes how the TLS connection for a call is established
+type TLSConfig struct {
+	CAFile     string `yaml:"ca-file,omitempty"`
+	CertFile   string `yaml:"cert-file,omitempty"`
+	KeyFile    string `yaml:"key-file,omitempty"`
+	ServerName string `yaml:"server-name,omitempty"`
+	MinVersion string `yaml:"min-version,omitempty"`
+}
+
+// ConnectionOptions configures how a call connects to its service. Plaintext, Authority and Metadata only
+// apply to grpc calls, and Proxy only to http calls
+type ConnectionOptions struct {
+	Plaintext bool              `yaml:"plaintext,omitempty"`
+	TLS       *TLSConfig        `yaml:"tls,omitempty"`
+	Authority string            `yaml:"authority,omitempty"`
+	Metadata  map[string]string `yaml:"metadata,omitempty"`
+	Proxy     string            `yaml:"proxy,omitempty"`
+}
+
+// withDefaults fills in any options not set from the given defaults. Metadata is merged, with keys already
+// present taking precedence
+func (c ConnectionOptions) withDefaults(defaults ConnectionOptions) ConnectionOptions {
+	if !c.Plaintext {
+		c.Plaintext = defaults.Plaintext
+	}
+	if c.TLS == nil {
+		c.TLS = defaults.TLS
+	}
+	if c.Authority == "" {
+		c.Authority = defaults.Authority
+	}
+	if c.Proxy == "" {
+		c.Proxy = defaults.Proxy
+	}
+	if defaults.Metadata != nil {
+		merged := make(map[string]string, len(defaults.Metadata)+len(c.Metadata))
+		for k, v := range defaults.Metadata {
+			merged[k] = v
+		}
+		for k, v := range c.Metadata {
+			merged[k] = v
+		}
+		c.Metadata = merged
+	}
+	return c
+}
+
 type Sequence struct {
-	Vars          map[string]any             `yaml:"vars"`
-	Imports       map[string]string          `yaml:"imports"`
-	Calls         []Call                     `yaml:"calls"`
-	path          string                     `yaml:"-"`
-	importedCalls map[string]map[string]Call `yaml:"-"`
+	Vars      map[string]any    `yaml:"vars"`
+	Imports   map[string]string `yaml:"imports"`
+	DependsOn []string          `yaml:"depends-on"`
+	// Matrix executes the sequence once for each combination of its values, given as variables
+	Matrix map[string][]any `yaml:"matrix"`
+	// Setup is executed before the calls, and teardown after them, even if they fail
+	Setup    []Call               `yaml:"setup"`
+	Calls    []Call               `yaml:"calls"`
+	Teardown []Call               `yaml:"teardown"`
+	file     string               `yaml:"-"`
+	path     string               `yaml:"-"`
+	imported map[string]*Sequence `yaml:"-"`
+	// importPositions records where each import was declared
+	importPositions map[string]Position `yaml:"-"`
+	// matrixVars holds the combination of matrix values this copy of the sequence is executed with
+	matrixVars map[string]any `yaml:"-"`
+
+	// Defaults for any calls in the sequence that don't specify their own
+	DescriptorSource  `yaml:",inline"`
+	ConnectionOptions `yaml:",inline"`
 }
 
 type Export struct {
-	JQ string `yaml:"jq,omitempty"`
-	As string `yaml:"as,omitempty"`
+	JQ    string  `yaml:"jq,omitempty"`
+	As    string  `yaml:"as,omitempty"`
+	Input JQInput `yaml:"input,omitempty"`
+
+	pos Position `yaml:"-"`
 }
 
 type Assert struct {
-	JQ       string `yaml:"jq,omitempty"`
-	Expected any    `yaml:"expected,omitempty"`
+	JQ       string   `yaml:"jq,omitempty"`
+	Op       AssertOp `yaml:"op,omitempty"`
+	Expected any      `yaml:"expected,omitempty"`
+	Input    JQInput  `yaml:"input,omitempty"`
+
+	pos Position `yaml:"-"`
+}
+
+func (a *Assert) GetOp() AssertOp {
+	if a.Op == "" {
+		return AssertOpEqual
+	}
+	return a.Op
 }
 
 type ImportedCall struct {
 	Name string `yaml:"name"`
 	Call string `yaml:"call"`
+	// With gives parameters to the imported call, available to it as variables for this invocation only
+	With map[string]any `yaml:"with,omitempty"`
+}
+
+// Param declares a parameter accepted by a call, given to it through the `with` of a from-import
+type Param struct {
+	Required bool `yaml:"required,omitempty"`
+	Default  any  `yaml:"default,omitempty"`
+}
+
+// Foreach repeats a call once for each item of a list, taken from exactly one of a variable, a jq
+// expression evaluated against the variables, or a CSV or JSON file
+type Foreach struct {
+	Var  string `yaml:"var,omitempty"`
+	JQ   string `yaml:"jq,omitempty"`
+	File string `yaml:"file,omitempty"`
+	// As is the name the item is available to the call as, defaults to item
+	As string `yaml:"as,omitempty"`
+	// Name is a template naming each iteration in reports, defaults to the index of the item
+	Name string `yaml:"name,omitempty"`
+}
+
+func (f *Foreach) GetAs() string {
+	if f.As == "" {
+		return "item"
+	}
+	return f.As
+}
+
+type Retry struct {
+	Attempts int           `yaml:"attempts,omitempty"`
+	Interval time.Duration `yaml:"interval,omitempty"`
+	Backoff  float64       `yaml:"backoff,omitempty"`
+	Until    *RetryUntil   `yaml:"until,omitempty"`
+}
+
+func (r *Retry) GetAttempts() int {
+	if r.Attempts <= 0 {
+		return 3
+	}
+	return r.Attempts
+}
+
+func (r *Retry) GetInterval() time.Duration {
+	if r.Interval <= 0 {
+		return time.Second
+	}
+	return r.Interval
+}
+
+func (r *Retry) GetBackoff() float64 {
+	if r.Backoff <= 0 {
+		return 1
+	}
+	return r.Backoff
+}
+
+// RetryUntil is the condition that stops a call from being retried. All given checks must pass
+type RetryUntil struct {
+	Status  []int    `yaml:"status,omitempty"`
+	Asserts []Assert `yaml:"asserts,omitempty"`
+}
+
+// Position is a location within a sequence file
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	switch {
+	case p.Line == 0:
+		return p.File
+	case p.Column == 0:
+		return fmt.Sprintf("%v:%v", p.File, p.Line)
+	default:
+		return fmt.Sprintf("%v:%v:%v", p.File, p.Line, p.Column)
+	}
+}
+
+// PositionError is an error caused by the part of a sequence file at Pos
+type PositionError struct {
+	Pos Position
+	Err error
+}
+
+func (p *PositionError) Error() string {
+	return fmt.Sprintf("%v: %v", p.Pos, p.Err)
+}
+
+func (p *PositionError) Unwrap() error {
+	return p.Err
+}
+
+// atPosition attributes err to pos, if the position is known
+func atPosition(pos Position, err error) error {
+	if err == nil || pos.File == "" {
+		return err
+	}
+	return &PositionError{Pos: pos, Err: err}
+}
+
+// positionErrors returns every *PositionError within the tree of err
+func positionErrors(err error) []*PositionError {
+	var posErrs []*PositionError
+	var walk func(err error)
+	walk = func(err error) {
+		if posErr, ok := err.(*PositionError); ok { //nolint:errorlint
+			posErrs = append(posErrs, posErr)
+			return
+		}
+		switch e := err.(type) { //nolint:errorlint
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				walk(inner)
+			}
+		}
+	}
+	walk(err)
+	return posErrs
 }
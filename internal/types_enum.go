@@ -11,6 +11,172 @@ import (
 	"strings"
 )
 
+const (
+	// AssertOpEqual is a AssertOp of type equal.
+	AssertOpEqual AssertOp = "equal"
+	// AssertOpNotEqual is a AssertOp of type not-equal.
+	AssertOpNotEqual AssertOp = "not-equal"
+	// AssertOpContains is a AssertOp of type contains.
+	AssertOpContains AssertOp = "contains"
+	// AssertOpMatches is a AssertOp of type matches.
+	AssertOpMatches AssertOp = "matches"
+	// AssertOpGt is a AssertOp of type gt.
+	AssertOpGt AssertOp = "gt"
+	// AssertOpGte is a AssertOp of type gte.
+	AssertOpGte AssertOp = "gte"
+	// AssertOpLt is a AssertOp of type lt.
+	AssertOpLt AssertOp = "lt"
+	// AssertOpLte is a AssertOp of type lte.
+	AssertOpLte AssertOp = "lte"
+	// AssertOpLength is a AssertOp of type length.
+	AssertOpLength AssertOp = "length"
+	// AssertOpExists is a AssertOp of type exists.
+	AssertOpExists AssertOp = "exists"
+	// AssertOpNotExists is a AssertOp of type not-exists.
+	AssertOpNotExists AssertOp = "not-exists"
+	// AssertOpType is a AssertOp of type type.
+	AssertOpType AssertOp = "type"
+	// AssertOpOneOf is a AssertOp of type one-of.
+	AssertOpOneOf AssertOp = "one-of"
+)
+
+var ErrInvalidAssertOp = fmt.Errorf("not a valid AssertOp, try [%s]", strings.Join(_AssertOpNames, ", "))
+
+var _AssertOpNames = []string{
+	string(AssertOpEqual),
+	string(AssertOpNotEqual),
+	string(AssertOpContains),
+	string(AssertOpMatches),
+	string(AssertOpGt),
+	string(AssertOpGte),
+	string(AssertOpLt),
+	string(AssertOpLte),
+	string(AssertOpLength),
+	string(AssertOpExists),
+	string(AssertOpNotExists),
+	string(AssertOpType),
+	string(AssertOpOneOf),
+}
+
+// AssertOpNames returns a list of possible string values of AssertOp.
+func AssertOpNames() []string {
+	tmp := make([]string, len(_AssertOpNames))
+	copy(tmp, _AssertOpNames)
+	return tmp
+}
+
+// String implements the Stringer interface.
+func (x AssertOp) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x AssertOp) IsValid() bool {
+	_, err := ParseAssertOp(string(x))
+	return err == nil
+}
+
+var _AssertOpValue = map[string]AssertOp{
+	"equal":      AssertOpEqual,
+	"not-equal":  AssertOpNotEqual,
+	"contains":   AssertOpContains,
+	"matches":    AssertOpMatches,
+	"gt":         AssertOpGt,
+	"gte":        AssertOpGte,
+	"lt":         AssertOpLt,
+	"lte":        AssertOpLte,
+	"length":     AssertOpLength,
+	"exists":     AssertOpExists,
+	"not-exists": AssertOpNotExists,
+	"type":       AssertOpType,
+	"one-of":     AssertOpOneOf,
+}
+
+// ParseAssertOp attempts to convert a string to a AssertOp.
+func ParseAssertOp(name string) (AssertOp, error) {
+	if x, ok := _AssertOpValue[name]; ok {
+		return x, nil
+	}
+	return AssertOp(""), fmt.Errorf("%s is %w", name, ErrInvalidAssertOp)
+}
+
+// MarshalText implements the text marshaller method.
+func (x AssertOp) MarshalText() ([]byte, error) {
+	return []byte(string(x)), nil
+}
+
+// UnmarshalText implements the text unmarshaller method.
+func (x *AssertOp) UnmarshalText(text []byte) error {
+	tmp, err := ParseAssertOp(string(text))
+	if err != nil {
+		return err
+	}
+	*x = tmp
+	return nil
+}
+
+const (
+	// JQInputBody is a JQInput of type body.
+	JQInputBody JQInput = "body"
+	// JQInputResponse is a JQInput of type response.
+	JQInputResponse JQInput = "response"
+)
+
+var ErrInvalidJQInput = fmt.Errorf("not a valid JQInput, try [%s]", strings.Join(_JQInputNames, ", "))
+
+var _JQInputNames = []string{
+	string(JQInputBody),
+	string(JQInputResponse),
+}
+
+// JQInputNames returns a list of possible string values of JQInput.
+func JQInputNames() []string {
+	tmp := make([]string, len(_JQInputNames))
+	copy(tmp, _JQInputNames)
+	return tmp
+}
+
+// String implements the Stringer interface.
+func (x JQInput) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x JQInput) IsValid() bool {
+	_, err := ParseJQInput(string(x))
+	return err == nil
+}
+
+var _JQInputValue = map[string]JQInput{
+	"body":     JQInputBody,
+	"response": JQInputResponse,
+}
+
+// ParseJQInput attempts to convert a string to a JQInput.
+func ParseJQInput(name string) (JQInput, error) {
+	if x, ok := _JQInputValue[name]; ok {
+		return x, nil
+	}
+	return JQInput(""), fmt.Errorf("%s is %w", name, ErrInvalidJQInput)
+}
+
+// MarshalText implements the text marshaller method.
+func (x JQInput) MarshalText() ([]byte, error) {
+	return []byte(string(x)), nil
+}
+
+// UnmarshalText implements the text unmarshaller method.
+func (x *JQInput) UnmarshalText(text []byte) error {
+	tmp, err := ParseJQInput(string(text))
+	if err != nil {
+		return err
+	}
+	*x = tmp
+	return nil
+}
+
 const (
 	// RequestTypeHttp is a RequestType of type http.
 	RequestTypeHttp RequestType = "http"
@@ -71,3 +237,64 @@ func (x *RequestType) UnmarshalText(text []byte) error {
 	*x = tmp
 	return nil
 }
+
+const (
+	// ResponseFormatJson is a ResponseFormat of type json.
+	ResponseFormatJson ResponseFormat = "json"
+	// ResponseFormatText is a ResponseFormat of type text.
+	ResponseFormatText ResponseFormat = "text"
+)
+
+var ErrInvalidResponseFormat = fmt.Errorf("not a valid ResponseFormat, try [%s]", strings.Join(_ResponseFormatNames, ", "))
+
+var _ResponseFormatNames = []string{
+	string(ResponseFormatJson),
+	string(ResponseFormatText),
+}
+
+// ResponseFormatNames returns a list of possible string values of ResponseFormat.
+func ResponseFormatNames() []string {
+	tmp := make([]string, len(_ResponseFormatNames))
+	copy(tmp, _ResponseFormatNames)
+	return tmp
+}
+
+// String implements the Stringer interface.
+func (x ResponseFormat) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x ResponseFormat) IsValid() bool {
+	_, err := ParseResponseFormat(string(x))
+	return err == nil
+}
+
+var _ResponseFormatValue = map[string]ResponseFormat{
+	"json": ResponseFormatJson,
+	"text": ResponseFormatText,
+}
+
+// ParseResponseFormat attempts to convert a string to a ResponseFormat.
+func ParseResponseFormat(name string) (ResponseFormat, error) {
+	if x, ok := _ResponseFormatValue[name]; ok {
+		return x, nil
+	}
+	return ResponseFormat(""), fmt.Errorf("%s is %w", name, ErrInvalidResponseFormat)
+}
+
+// MarshalText implements the text marshaller method.
+func (x ResponseFormat) MarshalText() ([]byte, error) {
+	return []byte(string(x)), nil
+}
+
+// UnmarshalText implements the text unmarshaller method.
+func (x *ResponseFormat) UnmarshalText(text []byte) error {
+	tmp, err := ParseResponseFormat(string(text))
+	if err != nil {
+		return err
+	}
+	*x = tmp
+	return nil
+}
@@ -0,0 +1,511 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/itchyny/gojq"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// jqVariableRef matches the fields a jq expression may read from the variables it's evaluated against
+var jqVariableRef = regexp.MustCompile(`\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Problem is an issue found while validating a sequence
+type Problem struct {
+	Pos     Position
+	Message string
+	// Warning is set for problems that don't prevent the sequence from executing
+	Warning bool
+}
+
+func (p Problem) String() string {
+	severity := "error"
+	if p.Warning {
+		severity = "warning"
+	}
+	return fmt.Sprintf("%v: %v: %v", p.Pos, severity, p.Message)
+}
+
+type ValidatorOpts struct {
+	Logger zerolog.Logger
+	Parser Parser
+	// Variables are those that will be given to the run, so that references to them aren't reported as
+	// undefined
+	Variables Variables
+	// Hooks is the hooks file that will be given to the run, whose setup exports are available to every
+	// sequence
+	Hooks string
+}
+
+func NewValidator(opts ValidatorOpts) *Validator {
+	return &Validator{
+		log:       opts.Logger,
+		parser:    opts.Parser,
+		variables: opts.Variables,
+		hooks:     opts.Hooks,
+		// Only used to resolve paths and template functions the same way as when executing
+		runner: NewRunner(RunnerOpts{Logger: opts.Logger, Parser: opts.Parser}),
+	}
+}
+
+// Validator statically checks sequences for problems that would otherwise only be found partway through
+// executing them
+type Validator struct {
+	log       zerolog.Logger
+	parser    Parser
+	variables Variables
+	hooks     string
+	runner    *Runner
+}
+
+// Validate checks every sequence at path, returning all of the problems found
+func (v *Validator) Validate(path string) ([]Problem, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error checking path: %w", err)
+	}
+
+	var problems []Problem
+	sequences := SequenceMap{}
+	// parseSequence parses the sequence at path, reporting why it couldn't be
+	parseSequence := func(path string, name string) (Sequence, bool) {
+		v.log.Debug().Str("sequence", name).Msg("validating sequence")
+		seq, err := v.parser.ParseSingleSequence(path)
+		if err != nil {
+			posErrs := positionErrors(err)
+			if len(posErrs) == 0 {
+				problems = append(problems, Problem{Pos: Position{File: path}, Message: err.Error()})
+			}
+			for _, posErr := range posErrs {
+				problems = append(problems, Problem{Pos: posErr.Pos, Message: posErr.Err.Error()})
+			}
+			return Sequence{}, false
+		}
+		return seq, true
+	}
+
+	var (
+		globals   map[string]bool
+		hooksInfo os.FileInfo
+	)
+	if v.hooks != "" {
+		hooksInfo, err = os.Stat(v.hooks)
+		if err != nil {
+			return nil, fmt.Errorf("error reading hooks: %w", err)
+		}
+		if hooks, ok := parseSequence(v.hooks, v.hooks); ok {
+			if len(hooks.Calls) > 0 {
+				problems = append(problems, Problem{Pos: Position{File: v.hooks}, Message: ErrHooksCalls.Error()})
+			}
+			var hooksProblems []Problem
+			hooksProblems, globals = v.validateSequence(hooks, nil, true)
+			problems = append(problems, hooksProblems...)
+		}
+	}
+	// The hooks file may live alongside the sequences, but isn't one itself
+	isHooks := func(path string) bool {
+		if hooksInfo == nil {
+			return false
+		}
+		seqInfo, err := os.Stat(path)
+		return err == nil && os.SameFile(seqInfo, hooksInfo)
+	}
+
+	if info.IsDir() {
+		err := walkSequenceFiles(path, func(path string, relPath string) error {
+			if isHooks(path) {
+				return nil
+			}
+			if seq, ok := parseSequence(path, relPath); ok {
+				sequences[relPath] = seq
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrWalkError, err)
+		}
+	} else if !isHooks(path) {
+		if seq, ok := parseSequence(path, path); ok {
+			sequences[path] = seq
+		}
+	}
+
+	if _, _, err := orderSequences(sequences); err != nil {
+		problems = append(problems, Problem{Pos: Position{File: path}, Message: err.Error()})
+	}
+
+	for _, seq := range sequences {
+		seqProblems, _ := v.validateSequence(seq, globals, false)
+		problems = append(problems, seqProblems...)
+	}
+
+	sort.SliceStable(problems, func(i, k int) bool {
+		a, b := problems[i].Pos, problems[k].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return problems, nil
+}
+
+// validateSequence checks seq, where globals are the variables exported by the setup of the hooks file.
+// isHooks is set when seq is the hooks file itself, whose setup exports are used by the other sequences.
+// Returns the problems found, along with the variables exported by the setup of seq
+func (v *Validator) validateSequence(seq Sequence, globals map[string]bool, isHooks bool) ([]Problem, map[string]bool) {
+	var problems []Problem
+	problem := func(pos Position, warning bool, format string, args ...any) {
+		if pos.File == "" {
+			pos.File = seq.file
+		}
+		problems = append(problems, Problem{Pos: pos, Message: fmt.Sprintf(format, args...), Warning: warning})
+	}
+
+	if err := v.runner.resolveImports(&seq, nil); err != nil {
+		posErrs := positionErrors(err)
+		if len(posErrs) == 0 {
+			problem(Position{}, false, "%v", err)
+		}
+		for _, posErr := range posErrs {
+			problem(posErr.Pos, false, "%v", posErr.Err)
+		}
+	}
+
+	defined := map[string]bool{}
+	layers := []map[string]any{v.variables.Env}
+	layers = append(layers, importedVars(&seq)...)
+	layers = append(layers, seq.Vars, v.variables.Overrides)
+	for k := range globals {
+		defined[k] = true
+	}
+	for _, vars := range layers {
+		for k := range vars {
+			defined[k] = true
+		}
+	}
+	for _, key := range sortedKeys(seq.Matrix) {
+		if err := checkMatrixValues(key, seq.Matrix[key]); err != nil {
+			problem(Position{}, false, "%v", err)
+		}
+		defined[key] = true
+	}
+
+	type export struct {
+		pos  Position
+		used bool
+	}
+	exports := map[string]*export{}
+
+	// checkRefs checks the variables referred to by the templates within value are defined
+	checkRefs := func(pos Position, value any, seqPath string, params map[string]bool) {
+		refs, err := v.templateReferences(value, seqPath)
+		if err != nil {
+			problem(pos, false, "error parsing call as template: %v", err)
+		}
+		for _, ref := range refs {
+			if exp, ok := exports[ref]; ok {
+				exp.used = true
+			} else if !defined[ref] && !params[ref] {
+				problem(pos, false, "template references undefined variable %v", ref)
+			}
+		}
+	}
+
+	// markJQUses marks any exports that jq expressions evaluated against the variables may refer to
+	markJQUses := func(exprs ...string) {
+		for _, expr := range exprs {
+			for _, match := range jqVariableRef.FindAllStringSubmatch(expr, -1) {
+				if exp, ok := exports[match[1]]; ok {
+					exp.used = true
+				}
+			}
+		}
+	}
+
+	// visit checks each of the calls of owner, params holds the names of the parameters given to them
+	var visit func(owner *Sequence, calls []Call, params map[string]bool)
+	visit = func(owner *Sequence, calls []Call, params map[string]bool) {
+		for _, c := range calls {
+			call, defining, withs, err := resolveImportedCall(owner, c)
+			if err != nil {
+				// Imports that couldn't be resolved have already been reported
+				if _, declared := owner.Imports[c.FromImport.Name]; !declared || !errors.Is(err, ErrUnknownImport) {
+					var posErr *PositionError
+					if errors.As(err, &posErr) {
+						problem(posErr.Pos, false, "%v", posErr.Err)
+					} else {
+						problem(c.pos, false, "%v", err)
+					}
+				}
+				continue
+			}
+
+			callParams := make(map[string]bool, len(params))
+			for name := range params {
+				callParams[name] = true
+			}
+			for _, w := range withs {
+				checkRefs(c.pos, w.with, w.seq.path, callParams)
+				for name := range w.with {
+					callParams[name] = true
+				}
+			}
+
+			// The item of a foreach is available to the step like a parameter
+			stepParams := params
+			foreach, foreachPath := c.Foreach, owner.path
+			if foreach == nil {
+				foreach, foreachPath = call.Foreach, defining.path
+			}
+			if foreach != nil {
+				for _, msg := range v.checkForeach(*foreach, foreachPath) {
+					problem(c.pos, false, "foreach: %v", msg)
+				}
+				markJQUses(foreach.JQ)
+				if foreach.Var != "" && !strings.Contains(foreach.Var, "{{") {
+					if exp, ok := exports[foreach.Var]; ok {
+						exp.used = true
+					} else if !defined[foreach.Var] && !callParams[foreach.Var] {
+						problem(c.pos, false, "foreach references undefined variable %v", foreach.Var)
+					}
+				}
+				stepParams = map[string]bool{foreach.GetAs(): true}
+				for name := range params {
+					stepParams[name] = true
+				}
+				callParams[foreach.GetAs()] = true
+			}
+
+			// Conditions of a from-import are evaluated before those of the call it refers to
+			if c.FromImport != nil {
+				for _, msg := range checkConditions(c) {
+					problem(c.pos, false, "%v", msg)
+				}
+				checkRefs(c.pos, Call{RunIf: c.RunIf, SkipIf: c.SkipIf}, owner.path, stepParams)
+				markJQUses(c.RunIf, c.SkipIf)
+			}
+
+			if call.FromImport != nil {
+				imported := defining.imported[call.FromImport.Name]
+				visit(imported, imported.Calls, callParams)
+				continue
+			}
+
+			for _, msg := range checkCallFields(call) {
+				problem(call.pos, false, "%v", msg)
+			}
+			for _, msg := range checkConditions(call) {
+				problem(call.pos, false, "%v", msg)
+			}
+			markJQUses(call.RunIf, call.SkipIf)
+
+			for _, name := range sortedKeys(call.Params) {
+				param := call.Params[name]
+				_, exported := exports[name]
+				if param.Required && param.Default == nil && !callParams[name] && !defined[name] && !exported {
+					problem(call.pos, false, "%v: %v", ErrMissingParameter, name)
+				}
+				callParams[name] = true
+			}
+
+			checkRefs(call.pos, call, defining.path, callParams)
+
+			for _, exp := range call.Exports {
+				if exp.As == "" {
+					problem(exp.pos, false, "export is missing as")
+				}
+				if msg := checkJQ(exp.JQ); msg != "" {
+					problem(exp.pos, false, "%v", msg)
+				}
+				exports[exp.As] = &export{pos: exp.pos}
+			}
+			asserts := call.Asserts
+			if call.Retry != nil && call.Retry.Until != nil {
+				asserts = append(asserts[:len(asserts):len(asserts)], call.Retry.Until.Asserts...)
+			}
+			for _, ass := range asserts {
+				if msg := checkJQ(ass.JQ); msg != "" {
+					problem(ass.pos, false, "%v", msg)
+				}
+			}
+		}
+	}
+	visit(&seq, seq.Setup, nil)
+	setupExports := make(map[string]bool, len(exports))
+	for name := range exports {
+		setupExports[name] = true
+	}
+	visit(&seq, seq.Calls, nil)
+	visit(&seq, seq.Teardown, nil)
+
+	for name, exp := range exports {
+		if !exp.used && name != "" && !(isHooks && setupExports[name]) {
+			problem(exp.pos, true, "export %v is never used", name)
+		}
+	}
+
+	return problems, setupExports
+}
+
+// checkCallFields checks that the fields required by the type of the call are given
+func checkCallFields(call Call) []string {
+	var msgs []string
+	if call.Url == "" {
+		msgs = append(msgs, "url is required")
+	}
+	switch call.GetType() {
+	case RequestTypeGrpc:
+		if call.ServiceHost == "" {
+			msgs = append(msgs, "service-host is required for grpc calls")
+		}
+	case RequestTypeHttp:
+		if call.bodyCount() > 1 {
+			msgs = append(msgs, ErrMultipleBodies.Error())
+		}
+	}
+	return msgs
+}
+
+// checkConditions checks the run-if and skip-if of the call are valid jq
+func checkConditions(call Call) []string {
+	var msgs []string
+	for _, cond := range []struct{ key, expr string }{{"run-if", call.RunIf}, {"skip-if", call.SkipIf}} {
+		if cond.expr == "" {
+			continue
+		}
+		if msg := checkJQ(cond.expr); msg != "" {
+			msgs = append(msgs, fmt.Sprintf("%v: %v", cond.key, msg))
+		}
+	}
+	return msgs
+}
+
+// checkForeach checks the foreach has a single valid source of items
+func (v *Validator) checkForeach(foreach Foreach, seqPath string) []string {
+	if foreach.sourceCount() != 1 {
+		return []string{ErrForeachSource.Error()}
+	}
+	switch {
+	case foreach.JQ != "":
+		if msg := checkJQ(foreach.JQ); msg != "" {
+			return []string{msg}
+		}
+	case foreach.File != "" && !strings.Contains(foreach.File, "{{"):
+		switch strings.ToLower(filepath.Ext(foreach.File)) {
+		case ".csv", ".json":
+		default:
+			return []string{fmt.Sprintf("unsupported file %v, must be .csv or .json", foreach.File)}
+		}
+		if _, err := os.Stat(v.runner.resolvePath(seqPath, foreach.File)); err != nil {
+			return []string{fmt.Sprintf("error reading file: %v", err)}
+		}
+	}
+	return nil
+}
+
+// checkJQ compiles a jq expression, returning why it's invalid. Expressions built from templates can't
+// be checked until they're executed
+func checkJQ(jq string) string {
+	if jq == "" {
+		return "jq is required"
+	}
+	if strings.Contains(jq, "{{") {
+		return ""
+	}
+	query, err := gojq.Parse(jq)
+	if err != nil {
+		return fmt.Sprintf("error parsing jq '%v': %v", jq, err)
+	}
+	if _, err := gojq.Compile(query); err != nil {
+		return fmt.Sprintf("error compiling jq '%v': %v", jq, err)
+	}
+	return ""
+}
+
+// templateReferences parses each string within value as a template, the same way they will be when
+// executed, returning the names of the variables they refer to
+func (v *Validator) templateReferences(value any, seqPath string) ([]string, error) {
+	var root yaml.Node
+	if err := root.Encode(value); err != nil {
+		return nil, err
+	}
+
+	var refs []string
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			refs = append(refs, n.Ident[0])
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				refs = append(refs, n.Ident[1])
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		// The body of range and with change what dot refers to, so only their pipelines can be checked
+		case *parse.RangeNode:
+			walk(n.Pipe)
+		case *parse.WithNode:
+			walk(n.Pipe)
+		}
+	}
+
+	funcs := v.runner.genFuncs(seqPath, nil)
+	var visit func(node *yaml.Node) error
+	visit = func(node *yaml.Node) error {
+		if !isTemplateNode(node) {
+			for _, child := range node.Content {
+				if err := visit(child); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		t, err := template.New("").Funcs(funcs).Parse(node.Value)
+		if err != nil {
+			return err
+		}
+		walk(t.Root)
+		return nil
+	}
+	if err := visit(&root); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		validator := NewValidator(ValidatorOpts{Parser: NewFSParser(FSParserOpts{})})
+
+		problems, err := validator.Validate("./testdata/validate/valid")
+		require.NoError(t, err)
+		require.Empty(t, problems)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		validator := NewValidator(ValidatorOpts{Parser: NewFSParser(FSParserOpts{})})
+
+		problems, err := validator.Validate("./testdata/validate/invalid.yaml")
+		require.NoError(t, err)
+
+		got := make([]string, 0, len(problems))
+		for _, problem := range problems {
+			got = append(got, problem.String())
+		}
+		require.Equal(t, []string{
+			"./testdata/validate/invalid.yaml: error: unknown dependency: ./testdata/validate/invalid.yaml depends on other.yaml, which is not part of this run",
+			"./testdata/validate/invalid.yaml:6:3: error: error parsing imported sequence 'lib/missing.yaml': error reading file: open testdata/validate/lib/missing.yaml: no such file or directory",
+			"./testdata/validate/invalid.yaml:9:3: error: unable to find import nope",
+			"./testdata/validate/invalid.yaml:12:3: error: unable to find call logout in imported sequence common",
+			"./testdata/validate/invalid.yaml:15:3: error: service-host is required for grpc calls",
+			"./testdata/validate/invalid.yaml:18:3: error: only one of body, body-raw, body-form, body-multipart or body-file may be given",
+			"./testdata/validate/invalid.yaml:18:3: error: template references undefined variable missing",
+			"./testdata/validate/invalid.yaml:24:5: warning: export id is never used",
+			"./testdata/validate/invalid.yaml:26:5: error: error parsing jq '.[ ': unexpected EOF",
+			"./testdata/validate/invalid.yaml:29:5: error: error compiling jq 'nofunc(1)': function not defined: nofunc/1",
+			"./testdata/validate/invalid.yaml:31:3: error: error parsing call as template: template: :1: unexpected \"}\" in operand",
+			"./testdata/validate/invalid.yaml:35:3: error: run-if: error parsing jq '.other ==': unexpected EOF",
+			"./testdata/validate/invalid.yaml:38:3: error: foreach references undefined variable nope",
+			"./testdata/validate/invalid.yaml:42:3: error: error parsing call as template: template: :1: unexpected {{end}}",
+		}, got)
+	})
+
+	t.Run("hooks", func(t *testing.T) {
+		validator := NewValidator(ValidatorOpts{
+			Parser: NewFSParser(FSParserOpts{}),
+			Hooks:  "./testdata/hooks/run/hooks.yaml",
+		})
+
+		problems, err := validator.Validate("./testdata/hooks/run")
+		require.NoError(t, err)
+		require.Empty(t, problems)
+
+		// Without the hooks file, its exports aren't defined
+		validator = NewValidator(ValidatorOpts{Parser: NewFSParser(FSParserOpts{})})
+		problems, err = validator.Validate("./testdata/hooks/run/users.yaml")
+		require.NoError(t, err)
+		require.Len(t, problems, 1)
+		require.Equal(t, "template references undefined variable token", problems[0].Message)
+	})
+
+	t.Run("nested imports", func(t *testing.T) {
+		validator := NewValidator(ValidatorOpts{Parser: NewFSParser(FSParserOpts{})})
+
+		problems, err := validator.Validate("./testdata/imports/main.yaml")
+		require.NoError(t, err)
+		require.Empty(t, problems)
+
+		problems, err = validator.Validate("./testdata/imports/cycle/a.yaml")
+		require.NoError(t, err)
+		require.Len(t, problems, 1)
+		require.Equal(t, Position{File: "testdata/imports/cycle/b.yaml", Line: 2, Column: 3}, problems[0].Pos)
+		require.Contains(t, problems[0].Message, "import cycle")
+	})
+
+	t.Run("foreach", func(t *testing.T) {
+		validator := NewValidator(ValidatorOpts{Parser: NewFSParser(FSParserOpts{})})
+
+		problems, err := validator.Validate("./testdata/foreach/main.yaml")
+		require.NoError(t, err)
+		// Items are only available within the foreach
+		require.Equal(t, []Problem{
+			{
+				Pos:     Position{File: "./testdata/foreach/main.yaml", Line: 29, Column: 3},
+				Message: "template references undefined variable item",
+			},
+		}, problems)
+	})
+
+	t.Run("matrix", func(t *testing.T) {
+		validator := NewValidator(ValidatorOpts{Parser: NewFSParser(FSParserOpts{})})
+
+		problems, err := validator.Validate("./testdata/matrix")
+		require.NoError(t, err)
+		require.Empty(t, problems)
+	})
+
+	t.Run("params", func(t *testing.T) {
+		validator := NewValidator(ValidatorOpts{Parser: NewFSParser(FSParserOpts{})})
+
+		problems, err := validator.Validate("./testdata/params/main.yaml")
+		require.NoError(t, err)
+		require.Equal(t, []Problem{
+			{
+				Pos:     Position{File: "./testdata/params/main.yaml", Line: 23, Column: 3},
+				Message: "template references undefined variable username",
+			},
+		}, problems)
+
+		problems, err = validator.Validate("./testdata/params/missing.yaml")
+		require.NoError(t, err)
+		require.Len(t, problems, 2)
+		require.Equal(t, "testdata/params/lib.yaml:2:3: error: missing required parameter: username", problems[0].String())
+	})
+
+	t.Run("run variables", func(t *testing.T) {
+		validator := NewValidator(ValidatorOpts{
+			Parser:    NewFSParser(FSParserOpts{}),
+			Variables: Variables{Env: map[string]any{"host": "some.api.com"}},
+		})
+
+		problems, err := validator.Validate("./testdata/validate/lib/common.yaml")
+		require.NoError(t, err)
+		require.Equal(t, []Problem{
+			{
+				Pos:     Position{File: "./testdata/validate/lib/common.yaml", Line: 5, Column: 5},
+				Message: "export token is never used",
+				Warning: true,
+			},
+		}, problems)
+	})
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvFileName is the name of the file, in the root of a run, defining the variables of each
+	// environment
+	EnvFileName = "poke.envs.yaml"
+	// EnvDirName is the name of the directory, in the root of a run, holding a variable file per
+	// environment
+	EnvDirName = "envs"
+)
+
+var ErrUnknownEnvironment = errors.New("unknown environment")
+
+// Variables holds the variables given from outside of the sequences themselves. Env variables are
+// layered underneath the vars of each sequence, while Overrides take precedence over them
+type Variables struct {
+	Env       map[string]any
+	Overrides map[string]any
+}
+
+type VariablesOpts struct {
+	// Root is the path being executed, environments are looked up relative to it (or its directory, if
+	// it's a file)
+	Root string
+	// Env is the name of the environment to load, if any
+	Env string
+	// VarFiles are paths to YAML files of variables, later files taking precedence over earlier ones
+	VarFiles []string
+	// Vars are `key=value` pairs, taking precedence over VarFiles
+	Vars []string
+}
+
+func LoadVariables(opts VariablesOpts) (Variables, error) {
+	vars := Variables{
+		Env:       map[string]any{},
+		Overrides: map[string]any{},
+	}
+
+	if opts.Env != "" {
+		env, err := loadEnvironment(opts.Root, opts.Env)
+		if err != nil {
+			return Variables{}, err
+		}
+		vars.Env = env
+	}
+
+	for _, path := range opts.VarFiles {
+		fileVars, err := readVarFile(path)
+		if err != nil {
+			return Variables{}, fmt.Errorf("error reading var file %v: %w", path, err)
+		}
+		for k, v := range fileVars {
+			vars.Overrides[k] = v
+		}
+	}
+
+	for _, kv := range opts.Vars {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return Variables{}, fmt.Errorf("invalid var '%v', expected key=value", kv)
+		}
+		vars.Overrides[key] = value
+	}
+
+	return vars, nil
+}
+
+// loadEnvironment reads the variables of the named environment, from either `envs/<name>.yaml` or the
+// `<name>` key of `poke.envs.yaml`
+func loadEnvironment(root string, name string) (map[string]any, error) {
+	if info, err := os.Stat(root); err == nil && !info.IsDir() {
+		root = filepath.Dir(root)
+	}
+
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(root, EnvDirName, name+ext)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		env, err := readVarFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading environment %v: %w", name, err)
+		}
+		return env, nil
+	}
+
+	path := filepath.Join(root, EnvFileName)
+	if _, err := os.Stat(path); err == nil {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %v: %w", EnvFileName, err)
+		}
+		var envs map[string]map[string]any
+		if err := yaml.Unmarshal(content, &envs); err != nil {
+			return nil, fmt.Errorf("error unmarshalling %v: %w", EnvFileName, err)
+		}
+		if env, ok := envs[name]; ok {
+			if env == nil {
+				env = map[string]any{}
+			}
+			return env, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %v, expected %v or a %v key in %v", ErrUnknownEnvironment, name, filepath.Join(root, EnvDirName, name+".yaml"), name, path)
+}
+
+func readVarFile(path string) (map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]any{}
+	if err := yaml.Unmarshal(content, &vars); err != nil {
+		return nil, fmt.Errorf("error unmarshalling: %w", err)
+	}
+	return vars, nil
+}
+
+// isVariableFile reports whether a path found while walking the root of a run holds variables rather
+// than a sequence
+func isVariableFile(root string, path string) bool {
+	if filepath.Base(path) == EnvFileName {
+		return true
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(rel, EnvDirName+string(filepath.Separator))
+}
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadVariables(t *testing.T) {
+	t.Run("env directory", func(t *testing.T) {
+		vars, err := LoadVariables(VariablesOpts{Root: "./testdata/vars", Env: "staging"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"service_host": "staging.some.api.com", "region": "us-east-1"}, vars.Env)
+		require.Empty(t, vars.Overrides)
+	})
+
+	t.Run("env file", func(t *testing.T) {
+		vars, err := LoadVariables(VariablesOpts{Root: "./testdata/vars/overrides.yaml", Env: "prod"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"service_host": "prod.some.api.com", "region": "eu-west-1"}, vars.Env)
+	})
+
+	t.Run("unknown env", func(t *testing.T) {
+		_, err := LoadVariables(VariablesOpts{Root: "./testdata/vars", Env: "dev"})
+		require.ErrorIs(t, err, ErrUnknownEnvironment)
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		vars, err := LoadVariables(VariablesOpts{
+			Root:     "./testdata/vars",
+			VarFiles: []string{"./testdata/vars/overrides.yaml"},
+			Vars:     []string{"region=ap-south-1", "token=a=b"},
+		})
+		require.NoError(t, err)
+		require.Empty(t, vars.Env)
+		require.Equal(t, map[string]any{"region": "ap-south-1", "retries": 3, "token": "a=b"}, vars.Overrides)
+	})
+
+	t.Run("invalid var", func(t *testing.T) {
+		_, err := LoadVariables(VariablesOpts{Vars: []string{"region"}})
+		require.ErrorContains(t, err, "expected key=value")
+	})
+}